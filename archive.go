@@ -0,0 +1,192 @@
+package vol
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrUnknownArchive = errors.New("unknown archive format")
+
+// Entry describes a file in an Archive.
+type Entry struct {
+	Name        Path
+	Size        int64  // size of the content, or -1 if it cannot be determined without decompressing
+	StoredSize  int64  // size as stored in the archive (e.g. compressed)
+	Compression string // compression method, as named by the archive format (e.g. "None" or "LZH" in vols)
+}
+
+// Archive is a set of named files in one of the formats the game engines load content from: a vol file (PVOL or VOL
+// format; see VolArchive), a Tribes 2 (and later Torque) VL2 file, which is a zip file (see VL2Archive), or a plain
+// directory (see DirArchive). Names are always Paths, separated by '\', and are looked up ignoring case unless the
+// archive was opened as case sensitive.
+type Archive interface {
+	// List returns all files in the archive, in archive order.
+	List() []Entry
+
+	// Stat returns the file named name. If there is none, the error wraps ErrNotFound.
+	Stat(name string) (Entry, error)
+
+	// Open returns the content of the file named name, decompressed. If there is none, the error wraps ErrNotFound; if
+	// its compression is not supported, the error wraps ErrUnsupportedCompression.
+	Open(name string) ([]byte, error)
+
+	// Write adds a file named name with content data, or replaces the content of the existing file named name (keeping
+	// its position). Changes are saved by Commit, except in DirArchives, where they are saved immediately.
+	Write(name string, data []byte) error
+
+	// Commit saves all changes made by Write. An archive in a single file is replaced atomically (see WriteFile).
+	Commit() error
+
+	// Close releases the resources of the archive. Uncommitted changes are discarded.
+	Close() error
+}
+
+// OpenArchive opens the archive at path, which may be a directory or a file in any format Archive supports, detected by
+// its magic bytes. If caseSensitive, names differing only in case are different files.
+func OpenArchive(path string, caseSensitive bool) (Archive, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	} else if stat.IsDir() {
+		return &DirArchive{Root: path, CaseSensitive: caseSensitive}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 4)
+	_, err = io.ReadFull(f, magic)
+	_ = f.Close()
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+
+	switch string(magic) {
+	case magicPVOL, magicVOL:
+		m, err := OpenMmap(path)
+		if err != nil {
+			return nil, err
+		}
+		m.CaseSensitive = caseSensitive
+		m.Reindex()
+		return &VolArchive{File: &m.File, Path: path, mapped: m}, nil
+	case zipMagicLocal, zipMagicEmpty:
+		return openVL2(path, caseSensitive)
+	default:
+		return nil, fmt.Errorf("%s: %w", path, ErrUnknownArchive)
+	}
+}
+
+// CreateArchive opens the archive at path as OpenArchive does or, if it does not exist, returns a new, empty archive to
+// be written there by Commit: a VL2Archive if path ends in .vl2 or .zip, and otherwise a VolArchive (in PVOL format).
+func CreateArchive(path string, caseSensitive bool) (Archive, error) {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return OpenArchive(path, caseSensitive)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".vl2", ".zip":
+		return &VL2Archive{Path: path, CaseSensitive: caseSensitive}, nil
+	default:
+		return &VolArchive{File: &File{CaseSensitive: caseSensitive}, Path: path}, nil
+	}
+}
+
+// VolArchive is an Archive of a vol file. Its File may be used directly for anything specific to vols (such as
+// compression, header fields, or Dedupe); it is written back to Path by Commit. As with File, Reindex must be called
+// after modifying Items directly.
+//
+// A name is looked up exactly first, and only then ignoring case, so that every item of a vol containing names that
+// differ only in case can still be opened by its own name.
+type VolArchive struct {
+	*File
+	Path string
+
+	mapped *MappedFile    // if opened from an existing file
+	exact  map[string]int // exact name -> index in Items; built lazily
+}
+
+func (a *VolArchive) List() []Entry {
+	entries := make([]Entry, len(a.Items))
+	for i := range a.Items {
+		entries[i] = a.Items[i].entry()
+	}
+	return entries
+}
+
+// Reindex rebuilds the name indexes of the archive (see File.Reindex).
+func (a *VolArchive) Reindex() {
+	a.File.Reindex()
+	a.exact = nil
+}
+
+func (a *VolArchive) lookup(name string) (*Item, bool) {
+	if a.exact == nil {
+		a.exact = make(map[string]int, len(a.Items))
+		for i, item := range a.Items {
+			a.exact[item.Filename] = i
+		}
+	}
+	if i, ok := a.exact[name]; ok {
+		return &a.Items[i], true
+	}
+	return a.Lookup(name)
+}
+
+func (a *VolArchive) Stat(name string) (Entry, error) {
+	item, ok := a.lookup(name)
+	if !ok {
+		return Entry{}, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	return item.entry(), nil
+}
+
+func (a *VolArchive) Open(name string) ([]byte, error) {
+	item, ok := a.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	return item.DecompressLimited(a.limits())
+}
+
+func (a *VolArchive) Write(name string, data []byte) error {
+	item := Item{Filename: name, Compression: None, Payload: data}
+	if old, ok := a.lookup(name); ok {
+		// Replace old in place; its name key (and so its entry in the File's index) is unchanged
+		i := a.exact[old.Filename]
+		delete(a.exact, old.Filename)
+		a.exact[name], *old = i, item
+		return nil
+	}
+	if err := a.Add(item); err != nil {
+		return err
+	}
+	a.exact[name] = len(a.Items) - 1
+	return nil
+}
+
+// Commit writes the vol to Path, in FormatPVOL (see File.VOLHeaders).
+func (a *VolArchive) Commit() error {
+	a.Format, a.VOLHeaders = FormatPVOL, nil
+	return WriteFile(a.Path, a.File)
+}
+
+func (a *VolArchive) Close() error {
+	if a.mapped == nil {
+		return nil
+	}
+	return a.mapped.Close()
+}
+
+func (v *Item) entry() Entry {
+	e := Entry{Name: Path(v.Filename), Size: -1, StoredSize: int64(len(v.Payload)), Compression: v.Compression.String()}
+	if size, ok := v.DecodedLen(); ok {
+		e.Size = size
+	}
+	return e
+}
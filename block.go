@@ -51,13 +51,13 @@ func (m *block) Parse(blockName string, expectMagic string, lenBits int, lenIncl
 	return nil
 }
 
-func (m block) Store(lenInclHeader bool, buf *ByteBuffer) {
-	payloadLen := uint32(len(m.Payload))
+// StoreHeader appends the header of a block with the given magic and payload length to buf. If lenInclHeader, the
+// stored length includes the length of the header itself (see Parse).
+func (m block) StoreHeader(payloadLen uint32, lenInclHeader bool, buf *ByteBuffer) {
 	if lenInclHeader {
 		payloadLen += blockHeaderLen
 	}
 
 	buf.AppendString(m.HeaderMagic)
 	binary.LittleEndian.PutUint32(buf.Extend(4), payloadLen)
-	buf.Append(m.Payload...)
 }
@@ -35,15 +35,32 @@ func (b *ByteBuffer) AppendString(s string) {
 	*b = append(*b, s...)
 }
 
+// Write appends p to b; it implements io.Writer, and never fails.
+func (b *ByteBuffer) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
+
+// Grow ensures that b has capacity for at least n more bytes, so that appending up to n bytes will not reallocate.
+func (b *ByteBuffer) Grow(n int) {
+	if cur := len(*b); cur+n > cap(*b) {
+		b2 := make([]byte, cur, cur+n)
+		copy(b2, *b)
+		*b = b2
+	}
+}
+
 // Extend adds n uninitialized bytes to b and returns that range as a slice.
 func (b *ByteBuffer) Extend(n int) ByteBuffer {
 	cur := len(*b)
-	if cur+n <= cap(*b) {
-		*b = (*b)[:cur+n]
-	} else {
-		b2 := make([]byte, cur+n, cur+2*n)
-		copy(b2, *b)
-		*b = b2
+	if cur+n > cap(*b) {
+		// Grow geometrically (like append), so that many small Extends take amortized constant time
+		newCap := 2 * cap(*b)
+		if newCap < cur+n {
+			newCap = cur + n
+		}
+		b.Grow(newCap - cur)
 	}
+	*b = (*b)[:cur+n]
 	return (*b)[cur : cur+n]
 }
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"fmt"
+	"github.com/iambob314/vol"
+)
+
+// openArchive opens the archive (vol, VL2 or directory) at fn, by its content (see vol.OpenArchive).
+func openArchive(fn string) (vol.Archive, error) {
+	a, err := vol.OpenArchive(fn, rootFlags.CaseSensitive)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", fn, err)
+	}
+	return a, nil
+}
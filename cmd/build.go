@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+// manifest describes how to build a vol file (see vol build), in JSON.
+type manifest struct {
+	Output      string          `json:"output,omitempty"`       // vol file to write, relative to the manifest's directory
+	Format      string          `json:"format,omitempty"`       // PVOL (default) or VOL
+	VOLHeaders  string          `json:"vol_headers,omitempty"`  // hex; required for VOL format (see vol.File.VOLHeaders)
+	Encoding    string          `json:"encoding,omitempty"`     // filename encoding; default --encoding
+	StrictNames string          `json:"strict_names,omitempty"` // check names against these rules (see --strict-names)
+	Dedupe      bool            `json:"dedupe,omitempty"`       // store identical payloads only once (see --dedupe)
+	Entries     []manifestEntry `json:"entries"`                // files to pack, in order
+}
+
+type manifestEntry struct {
+	Source      string `json:"source"`                // file to pack, relative to the manifest's directory ('/' or '\' separated)
+	Name        string `json:"name,omitempty"`        // name in the vol; default is Source
+	Compression string `json:"compression,omitempty"` // default None
+	Unknown1    uint32 `json:"unknown1,omitempty"`    // item header fields of unknown purpose (see vol.Item.Unknown1)
+	Unknown2    uint32 `json:"unknown2,omitempty"`
+}
+
+func readManifest(fn string) (*manifest, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest %s: %w", fn, err)
+	}
+	defer f.Close()
+
+	var m manifest
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("could not parse manifest %s: %w", fn, err)
+	}
+	return &m, nil
+}
+
+// Build builds the vol described by m. Source paths are relative to host directory dir.
+func (m *manifest) Build(dir string) (*vol.File, error) {
+	var (
+		v   vol.File
+		err error
+	)
+	v.CaseSensitive = rootFlags.CaseSensitive
+	v.Dedupe = m.Dedupe
+
+	if m.Format != "" {
+		if v.Format, err = vol.ParseFormat(m.Format); err != nil {
+			return nil, err
+		}
+	}
+	if m.VOLHeaders != "" {
+		if v.VOLHeaders, err = hex.DecodeString(m.VOLHeaders); err != nil {
+			return nil, fmt.Errorf("invalid vol_headers: %w", err)
+		}
+	}
+	if m.Encoding != "" {
+		if v.Encoding, err = vol.ParseEncoding(m.Encoding); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range m.Entries {
+		fn, err := vol.Path(e.Source).JoinHost(dir)
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: invalid source: %w", e.Source, err)
+		}
+		name := vol.Path(e.Name)
+		if name == "" {
+			name = vol.Path(e.Source).Clean()
+		}
+
+		compression := vol.None
+		if e.Compression != "" {
+			if compression, err = vol.ParseCompressionType(e.Compression); err != nil {
+				return nil, fmt.Errorf("entry %s: %w", name, err)
+			}
+		}
+
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			return nil, fmt.Errorf("could not read input file %s: %w", fn, err)
+		}
+		item := vol.Item{Filename: string(name), Compression: vol.None, Payload: data, Unknown1: e.Unknown1, Unknown2: e.Unknown2}
+		if err := item.Recompress(compression); err != nil {
+			return nil, fmt.Errorf("could not compress %s: %w", fn, err)
+		} else if err := v.Add(item); err != nil {
+			return nil, fmt.Errorf("could not pack %s: %w", fn, err)
+		}
+		fmt.Printf("packing %s (as %s)\n", fn, name)
+	}
+
+	if err := checkStrictNames(m.StrictNames, itemNames(v.Items)); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// manifestFor returns a manifest that rebuilds v, assuming it is unpacked into the manifest's directory. As build can
+// only store uncompressed files (and unpack cannot extract compressed ones), an error wrapping
+// vol.ErrUnsupportedCompression is returned if any item of v is compressed.
+func manifestFor(v *vol.File, volFN string) (*manifest, error) {
+	m := &manifest{
+		Output:   filepath.Base(volFN),
+		Format:   v.Format.String(),
+		Encoding: vol.DefaultEncoding.String(),
+		Dedupe:   v.Dedupe,
+		Entries:  make([]manifestEntry, 0, len(v.Items)),
+	}
+	if v.Encoding != vol.EncodingDefault {
+		m.Encoding = v.Encoding.String()
+	}
+	if v.Format == vol.FormatVOL {
+		m.VOLHeaders = hex.EncodeToString(v.VOLHeaders)
+	}
+
+	for _, item := range v.Items {
+		if item.Compression != vol.None {
+			return nil, fmt.Errorf("%s is %s compressed, which build cannot store: %w", item.Filename, item.Compression, vol.ErrUnsupportedCompression)
+		}
+		m.Entries = append(m.Entries, manifestEntry{
+			Source:      filepath.ToSlash(vol.Path(item.Filename).Clean().HostPath()),
+			Name:        item.Filename,
+			Compression: item.Compression.String(),
+			Unknown1:    item.Unknown1,
+			Unknown2:    item.Unknown2,
+		})
+	}
+	return m, nil
+}
+
+var buildCmd = &cobra.Command{
+	Use:  "build manifest.json [volfile]",
+	Long: "vol build builds a .vol file from a JSON manifest listing the files to pack, their names and compression,\nin order (see vol manifest). The vol is written to volfile, or else the manifest's output.",
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifestFN := args[0]
+		m, err := readManifest(manifestFN)
+		if err != nil {
+			return err
+		}
+
+		dir := filepath.Dir(manifestFN)
+		volFN := filepath.Join(dir, filepath.FromSlash(m.Output))
+		if len(args) > 1 {
+			volFN = args[1]
+		} else if m.Output == "" {
+			return fmt.Errorf("manifest %s has no output; specify volfile", manifestFN)
+		}
+
+		v, err := m.Build(dir)
+		if err != nil {
+			return err
+		}
+		return vol.WriteFile(volFN, v)
+	},
+}
+
+var manifestCmd = &cobra.Command{
+	Use:  "manifest volfile",
+	Long: "vol manifest prints a JSON manifest (see vol build) that rebuilds a .vol file from its unpacked contents",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volFN := args[0]
+
+		var v vol.File
+		if data, err := os.ReadFile(volFN); err != nil {
+			return fmt.Errorf("could not read file %s: %w", volFN, err)
+		} else if err := v.Parse(data); err != nil {
+			return fmt.Errorf("could not parse file %s: %w", volFN, err)
+		}
+
+		m, err := manifestFor(&v, volFN)
+		if err != nil {
+			return fmt.Errorf("cannot write a manifest for %s: %w", volFN, err)
+		}
+		out, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return err
+		}
+		out = append(out, '\n')
+
+		if manifestFlags.Output == "" {
+			_, err = os.Stdout.Write(out)
+			return err
+		}
+		return os.WriteFile(manifestFlags.Output, out, 0666)
+	},
+}
+
+var manifestFlags struct {
+	Output string
+}
+
+func init() {
+	manifestCmd.Flags().StringVarP(&manifestFlags.Output, "output", "o", "", "write the manifest to this file rather than stdout")
+}
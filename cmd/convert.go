@@ -0,0 +1,447 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// convertEntry is a file converted between a vol and a zip or tar archive.
+type convertEntry struct {
+	Name string // in the archive, '/' separated
+	Data []byte
+	Meta *entryMeta // nil if the archive has no vol metadata for the file
+}
+
+// entryMeta is the vol-specific metadata of a file, kept in a zip extra field or in PAX records so that a vol can be
+// exported and imported again without losing anything.
+type entryMeta struct {
+	Name               string // exact name in the vol
+	Compression        vol.CompressionType
+	Raw                bool // Data is the compressed payload, since it could not be decompressed
+	Unknown1, Unknown2 uint32
+}
+
+// archiveMeta is the metadata of the vol as a whole, kept in the zip comment or a PAX global header.
+type archiveMeta struct {
+	Format     vol.Format
+	VOLHeaders []byte
+	Encoding   vol.Encoding
+	Dedupe     bool
+}
+
+const (
+	zipExtraID      = 0x6c76 // "vl"; ID of the zip extra field holding entryMeta
+	zipExtraVersion = 1
+	zipCommentKey   = "vol:" // prefix of the zip comment holding archiveMeta
+	paxPrefix       = "VOL." // prefix of the keys of PAX records holding entryMeta and archiveMeta
+)
+
+// archiveKind is a kind of archive that vol can export to and import from.
+type archiveKind string
+
+const (
+	kindZip   = archiveKind("zip")
+	kindTar   = archiveKind("tar")
+	kindTarGz = archiveKind("tar.gz")
+)
+
+// archiveKindByName returns the kind of archive named fn, by its extension.
+func archiveKindByName(fn string) (archiveKind, error) {
+	lower := strings.ToLower(fn)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return kindZip, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return kindTar, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return kindTarGz, nil
+	default:
+		return "", fmt.Errorf("unknown archive type for %s (expected .zip, .tar, .tar.gz or .tgz)", fn)
+	}
+}
+
+// archiveKindByMagic returns the kind of archive with content data, by its magic bytes.
+func archiveKindByMagic(data []byte) (archiveKind, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return kindZip, nil
+	case bytes.HasPrefix(data, []byte("\x1f\x8b")):
+		return kindTarGz, nil
+	case len(data) >= 262 && string(data[257:262]) == "ustar":
+		return kindTar, nil
+	default:
+		return "", fmt.Errorf("not a zip or tar archive")
+	}
+}
+
+func (m *entryMeta) zipExtra() []byte {
+	var extra vol.ByteBuffer
+	binary.LittleEndian.PutUint16(extra.Extend(2), zipExtraID)
+	binary.LittleEndian.PutUint16(extra.Extend(2), uint16(11+len(m.Name)))
+	extra.Append(zipExtraVersion, byte(m.Compression), 0)
+	if m.Raw {
+		extra[len(extra)-1] = 1
+	}
+	binary.LittleEndian.PutUint32(extra.Extend(4), m.Unknown1)
+	binary.LittleEndian.PutUint32(extra.Extend(4), m.Unknown2)
+	extra.AppendString(m.Name)
+	return extra
+}
+
+// parseZipExtra returns the entryMeta in zip extra fields extra, if any.
+func parseZipExtra(extra []byte) *entryMeta {
+	buf := vol.ByteBuffer(extra)
+	for {
+		hdr, ok := buf.Next(4)
+		if !ok {
+			return nil
+		}
+		id, size := binary.LittleEndian.Uint16(hdr), binary.LittleEndian.Uint16(hdr[2:])
+		data, ok := buf.Next(int(size))
+		if !ok {
+			return nil
+		} else if id != zipExtraID || len(data) < 11 || data[0] != zipExtraVersion {
+			continue
+		}
+		return &entryMeta{
+			Compression: vol.CompressionType(data[1]),
+			Raw:         data[2] == 1,
+			Unknown1:    binary.LittleEndian.Uint32(data[3:]),
+			Unknown2:    binary.LittleEndian.Uint32(data[7:]),
+			Name:        string(data[11:]),
+		}
+	}
+}
+
+func (m *entryMeta) paxRecords() map[string]string {
+	records := map[string]string{
+		paxPrefix + "name":        m.Name,
+		paxPrefix + "compression": m.Compression.String(),
+		paxPrefix + "unknown1":    strconv.FormatUint(uint64(m.Unknown1), 10),
+		paxPrefix + "unknown2":    strconv.FormatUint(uint64(m.Unknown2), 10),
+	}
+	if m.Raw {
+		records[paxPrefix+"raw"] = "1"
+	}
+	return records
+}
+
+// parsePAXRecords returns the entryMeta in PAX records, if any.
+func parsePAXRecords(records map[string]string) (*entryMeta, error) {
+	name, ok := records[paxPrefix+"name"]
+	if !ok {
+		return nil, nil
+	}
+	m := &entryMeta{Name: name, Raw: records[paxPrefix+"raw"] == "1"}
+	var err error
+	if m.Compression, err = vol.ParseCompressionType(records[paxPrefix+"compression"]); err != nil {
+		return nil, err
+	}
+	for key, field := range map[string]*uint32{"unknown1": &m.Unknown1, "unknown2": &m.Unknown2} {
+		n, err := strconv.ParseUint(records[paxPrefix+key], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s%s record: %w", paxPrefix, key, err)
+		}
+		*field = uint32(n)
+	}
+	return m, nil
+}
+
+func (m *archiveMeta) zipComment() string {
+	return fmt.Sprintf("%sformat=%s vol_headers=%s encoding=%s dedupe=%t", zipCommentKey, m.Format, hex.EncodeToString(m.VOLHeaders), m.Encoding, m.Dedupe)
+}
+
+func (m *archiveMeta) paxRecords() map[string]string {
+	return map[string]string{
+		paxPrefix + "format":      m.Format.String(),
+		paxPrefix + "vol_headers": hex.EncodeToString(m.VOLHeaders),
+		paxPrefix + "encoding":    m.Encoding.String(),
+		paxPrefix + "dedupe":      strconv.FormatBool(m.Dedupe),
+	}
+}
+
+// parseArchiveMeta parses archiveMeta from key-value pairs (from a zip comment or PAX records, without prefix).
+func parseArchiveMeta(get func(key string) string) (*archiveMeta, error) {
+	var (
+		m   archiveMeta
+		err error
+	)
+	if m.Format, err = vol.ParseFormat(get("format")); err != nil {
+		return nil, err
+	} else if m.VOLHeaders, err = hex.DecodeString(get("vol_headers")); err != nil {
+		return nil, fmt.Errorf("invalid vol_headers: %w", err)
+	} else if m.Encoding, err = vol.ParseEncoding(get("encoding")); err != nil {
+		return nil, err
+	}
+	m.Dedupe = get("dedupe") == "true"
+	return &m, nil
+}
+
+// writeArchive writes entries to w as an archive of the given kind. If meta is not nil, it and each entry's Meta are
+// stored too. All files get modification time modTime.
+func writeArchive(w io.Writer, kind archiveKind, entries []convertEntry, meta *archiveMeta, modTime time.Time) error {
+	if kind == kindZip {
+		zw := zip.NewWriter(w)
+		for _, e := range entries {
+			hdr := &zip.FileHeader{Name: e.Name, Method: zip.Deflate, Modified: modTime}
+			if meta != nil {
+				hdr.Extra = e.Meta.zipExtra()
+			}
+			f, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return err
+			} else if _, err := f.Write(e.Data); err != nil {
+				return err
+			}
+		}
+		if meta != nil {
+			if err := zw.SetComment(meta.zipComment()); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	}
+
+	var gw *gzip.Writer
+	if kind == kindTarGz {
+		gw = gzip.NewWriter(w)
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	if meta != nil {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: meta.paxRecords(), Format: tar.FormatPAX}); err != nil {
+			return err
+		}
+	}
+	for _, e := range entries {
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: e.Name, Size: int64(len(e.Data)), Mode: 0644, ModTime: modTime}
+		if meta != nil {
+			hdr.PAXRecords, hdr.Format = e.Meta.paxRecords(), tar.FormatPAX
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		} else if _, err := tw.Write(e.Data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gw != nil {
+		return gw.Close()
+	}
+	return nil
+}
+
+// readArchive reads the files in data, an archive of the given kind, along with any vol metadata. Directories and
+// other special files are skipped.
+func readArchive(data []byte, kind archiveKind) ([]convertEntry, *archiveMeta, error) {
+	maxSize := vol.DefaultLimits.MaxItemSize
+	readAll := func(r io.Reader, name string) ([]byte, error) {
+		if maxSize > 0 {
+			r = io.LimitReader(r, maxSize+1)
+		}
+		content, err := io.ReadAll(r)
+		if err == nil && maxSize > 0 && int64(len(content)) > maxSize {
+			err = fmt.Errorf("%s: %w (%d bytes)", name, vol.ErrLimitExceeded, maxSize)
+		}
+		return content, err
+	}
+
+	var (
+		entries []convertEntry
+		meta    *archiveMeta
+	)
+	if kind == kindZip {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, nil, err
+		}
+		if comment := strings.TrimPrefix(zr.Comment, zipCommentKey); comment != zr.Comment {
+			fields := make(map[string]string)
+			for _, field := range strings.Fields(comment) {
+				if key, value, ok := strings.Cut(field, "="); ok {
+					fields[key] = value
+				}
+			}
+			if meta, err = parseArchiveMeta(func(key string) string { return fields[key] }); err != nil {
+				return nil, nil, fmt.Errorf("invalid vol metadata in zip comment: %w", err)
+			}
+		}
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			content, err := readAll(r, f.Name)
+			_ = r.Close()
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			entries = append(entries, convertEntry{Name: f.Name, Data: content, Meta: parseZipExtra(f.Extra)})
+		}
+		return entries, meta, nil
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if kind == kindTarGz {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = gr
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, meta, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeXGlobalHeader:
+			if _, ok := hdr.PAXRecords[paxPrefix+"format"]; ok {
+				if meta, err = parseArchiveMeta(func(key string) string { return hdr.PAXRecords[paxPrefix+key] }); err != nil {
+					return nil, nil, fmt.Errorf("invalid vol metadata in tar global header: %w", err)
+				}
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			content, err := readAll(tr, hdr.Name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+			entryMeta, err := parsePAXRecords(hdr.PAXRecords)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", hdr.Name, err)
+			}
+			entries = append(entries, convertEntry{Name: hdr.Name, Data: content, Meta: entryMeta})
+		}
+	}
+}
+
+var exportCmd = &cobra.Command{
+	Use:  "export volfile out.zip|out.tar|out.tar.gz",
+	Long: "vol export converts a .vol file to a zip or tar archive (by the output's extension): names use / rather than \\,\nand files are decompressed. Vol-specific metadata (exact names, compression, header fields, format) is kept in zip\nextra fields or PAX records, so that vol import can rebuild a vol with equivalent contents (identical if the original\nwas in standard layout, as vol pack writes; use vol explode to keep any other layout); use --metadata=false to leave\nit out.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volFN, outFN := args[0], args[1]
+		kind, err := archiveKindByName(outFN)
+		if err != nil {
+			return err
+		}
+
+		v, err := vol.OpenMmap(volFN)
+		if err != nil {
+			return fmt.Errorf("could not open file %s: %w", volFN, err)
+		}
+		defer v.Close()
+		stat, err := os.Stat(volFN)
+		if err != nil {
+			return err
+		}
+
+		entries := make([]convertEntry, 0, len(v.Items))
+		for i := range v.Items {
+			item := &v.Items[i]
+			content, decompressed, err := itemContent(item)
+			if err != nil {
+				return fmt.Errorf("could not decompress %s: %w", item.Filename, err)
+			}
+
+			// Names that are unsafe as paths are replaced, as by explode; only the metadata keeps them
+			name := vol.Path(item.Filename)
+			archiveName := strings.ReplaceAll(string(name.Clean()), string(vol.PathSeparator), "/")
+			msg := ""
+			if name.CheckSafe() != nil {
+				archiveName, msg = rawItemsDir+"/"+strconv.Itoa(i), " (not a safe path)"
+			}
+
+			entries = append(entries, convertEntry{
+				Name: archiveName,
+				Data: content,
+				Meta: &entryMeta{Name: item.Filename, Compression: item.Compression, Raw: !decompressed, Unknown1: item.Unknown1, Unknown2: item.Unknown2},
+			})
+			fmt.Printf("exporting %s (as %s)%s\n", item.Filename, archiveName, msg)
+		}
+
+		var meta *archiveMeta
+		if exportFlags.Metadata {
+			meta = &archiveMeta{Format: v.Format, VOLHeaders: v.VOLHeaders, Encoding: vol.DefaultEncoding, Dedupe: v.Dedupe}
+		}
+		var out bytes.Buffer
+		if err := writeArchive(&out, kind, entries, meta, stat.ModTime()); err != nil {
+			return fmt.Errorf("could not write %s: %w", outFN, err)
+		}
+		return vol.WriteFileFrom(outFN, &out)
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:  "import in.zip|in.tar|in.tar.gz volfile",
+	Long: "vol import converts a zip or tar archive to a .vol file: names use \\ rather than /, and files are recompressed\nas recorded in the archive's vol metadata (see vol export), if any. Directories are not packed.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inFN, volFN := args[0], args[1]
+
+		data, err := os.ReadFile(inFN)
+		if err != nil {
+			return fmt.Errorf("could not read file %s: %w", inFN, err)
+		}
+		kind, err := archiveKindByMagic(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", inFN, err)
+		}
+		entries, meta, err := readArchive(data, kind)
+		if err != nil {
+			return fmt.Errorf("could not read %s archive %s: %w", kind, inFN, err)
+		}
+
+		var v vol.File
+		v.CaseSensitive = rootFlags.CaseSensitive
+		if meta != nil {
+			v.Format, v.VOLHeaders, v.Encoding, v.Dedupe = meta.Format, meta.VOLHeaders, meta.Encoding, meta.Dedupe
+		}
+		for _, e := range entries {
+			item := vol.Item{Filename: string(vol.Path(strings.ReplaceAll(e.Name, "/", string(vol.PathSeparator))).Clean()), Compression: vol.None, Payload: e.Data}
+			if m := e.Meta; m != nil {
+				item.Filename, item.Unknown1, item.Unknown2 = m.Name, m.Unknown1, m.Unknown2
+				if m.Raw {
+					item.Compression = m.Compression
+				} else if err := item.Recompress(m.Compression); err != nil {
+					return fmt.Errorf("could not compress %s: %w", e.Name, err)
+				}
+			}
+
+			if err := v.Add(item); err != nil {
+				return fmt.Errorf("could not pack %s: %w", e.Name, err)
+			}
+			fmt.Printf("packing %s (as %s)\n", e.Name, item.Filename)
+		}
+		return vol.WriteFile(volFN, &v)
+	},
+}
+
+var exportFlags struct {
+	Metadata bool
+}
+
+func init() {
+	exportCmd.Flags().BoolVar(&exportFlags.Metadata, "metadata", true, "store vol metadata in the archive, so the vol can be imported again with equivalent contents")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+	"unicode/utf8"
+)
+
+// itemContent returns the decompressed content of item. If its compression is not supported, the raw payload is
+// returned instead, with decompressed false.
+func itemContent(item *vol.Item) (content []byte, decompressed bool, err error) {
+	content, err = item.Decompress()
+	if errors.Is(err, vol.ErrUnsupportedCompression) {
+		return item.Payload, false, nil
+	}
+	return content, err == nil, err
+}
+
+// isText reports whether data looks like text (and so can be shown as lines): like git, whether its first 8000 bytes
+// contain no NUL bytes.
+func isText(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) < 0
+}
+
+// diffItem is an item of one of the archives being compared, with its content. Files of archives other than vols are
+// represented as uncompressed items.
+type diffItem struct {
+	*vol.Item
+	Content      []byte
+	Decompressed bool
+	Hash         [sha256.Size]byte // of Content (and, if not Decompressed, Compression)
+}
+
+func loadDiffItems(a vol.Archive) ([]diffItem, error) {
+	v, ok := a.(*vol.VolArchive)
+	if !ok {
+		entries := a.List()
+		items := make([]diffItem, len(entries))
+		for i, e := range entries {
+			content, err := a.Open(string(e.Name))
+			if err != nil {
+				return nil, fmt.Errorf("could not read %s: %w", e.Name, err)
+			}
+			item := &vol.Item{Filename: string(e.Name), Compression: vol.None, Payload: content}
+			items[i] = diffItem{Item: item, Content: content, Decompressed: true, Hash: sha256.Sum256(content)}
+		}
+		return items, nil
+	}
+
+	items := make([]diffItem, len(v.Items))
+	for i := range v.Items {
+		item := &v.Items[i]
+		content, decompressed, err := itemContent(item)
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress %s: %w", item.Filename, err)
+		}
+		items[i] = diffItem{Item: item, Content: content, Decompressed: decompressed, Hash: sha256.Sum256(content)}
+		if !decompressed {
+			items[i].Hash = sha256.Sum256(append([]byte{byte(item.Compression)}, content...))
+		}
+	}
+	return items, nil
+}
+
+var diffCmd = &cobra.Command{
+	Use:  "diff a.vol b.vol",
+	Long: "vol diff lists the differences between two .vol files (or .vl2 files, or directories): files added, removed,\nrenamed (detected by content), modified, or with changed compression or header fields. With --text, text files'\nchanges are shown as unified diffs.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		aFN, bFN := args[0], args[1]
+
+		var (
+			items   [2][]diffItem
+			byName  [2]map[string]*diffItem // name key (see vol.Path.Key) -> item
+			byExact [2]map[string]*diffItem // exact name -> item, preferred (as in vol.VolArchive) when names differ only in case
+		)
+		for i, fn := range []string{aFN, bFN} {
+			a, err := openArchive(fn)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			if items[i], err = loadDiffItems(a); err != nil {
+				return fmt.Errorf("%s: %w", fn, err)
+			}
+			byName[i], byExact[i] = make(map[string]*diffItem, len(items[i])), make(map[string]*diffItem, len(items[i]))
+			for j := range items[i] {
+				byName[i][vol.Path(items[i][j].Filename).Key(rootFlags.CaseSensitive)] = &items[i][j]
+				byExact[i][vol.Path(items[i][j].Filename).Key(true)] = &items[i][j]
+			}
+		}
+		lookup := func(i int, name string) *diffItem {
+			if item, ok := byExact[i][vol.Path(name).Key(true)]; ok {
+				return item
+			}
+			return byName[i][vol.Path(name).Key(rootFlags.CaseSensitive)]
+		}
+		inA := func(name string) *diffItem { return lookup(0, name) }
+		inB := func(name string) *diffItem { return lookup(1, name) }
+
+		showText := func(aItem, bItem *diffItem) {
+			if !diffFlags.Text {
+				return
+			}
+			var aText, bText []byte
+			aName, bName := "/dev/null", "/dev/null"
+			if aItem != nil {
+				if !aItem.Decompressed || !isText(aItem.Content) {
+					return
+				}
+				aText, aName = aItem.Content, aFN+":"+aItem.Filename
+			}
+			if bItem != nil {
+				if !bItem.Decompressed || !isText(bItem.Content) {
+					return
+				}
+				bText, bName = bItem.Content, bFN+":"+bItem.Filename
+			}
+			fmt.Print(unifiedDiff(aName, bName, decodeText(aText), decodeText(bText)))
+		}
+
+		// Files only in a or b, which may be renames of each other
+		var removed, added []*diffItem
+		for i := range items[0] {
+			if inB(items[0][i].Filename) == nil {
+				removed = append(removed, &items[0][i])
+			}
+		}
+		addedByHash := make(map[[sha256.Size]byte][]*diffItem)
+		for i := range items[1] {
+			if inA(items[1][i].Filename) == nil {
+				added = append(added, &items[1][i])
+				addedByHash[items[1][i].Hash] = append(addedByHash[items[1][i].Hash], &items[1][i])
+			}
+		}
+		renamedTo := make(map[*diffItem]*diffItem)
+		renamed := make(map[*diffItem]bool) // added items that are renames
+		for _, ai := range removed {
+			if candidates := addedByHash[ai.Hash]; len(candidates) > 0 {
+				renamedTo[ai], renamed[candidates[0]] = candidates[0], true
+				addedByHash[ai.Hash] = candidates[1:]
+			}
+		}
+
+		for _, ai := range removed {
+			if bi, ok := renamedTo[ai]; ok {
+				fmt.Printf("renamed:     %s -> %s\n", ai.Filename, bi.Filename)
+				printMetaChanges(bi.Filename, ai, bi)
+			} else {
+				fmt.Printf("removed:     %s\n", ai.Filename)
+				showText(ai, nil)
+			}
+		}
+		for _, bi := range added {
+			if !renamed[bi] {
+				fmt.Printf("added:       %s\n", bi.Filename)
+				showText(nil, bi)
+			}
+		}
+
+		// Files in both
+		for i := range items[0] {
+			ai := &items[0][i]
+			bi := inB(ai.Filename)
+			if bi == nil {
+				continue
+			}
+
+			if ai.Filename != bi.Filename {
+				fmt.Printf("renamed:     %s -> %s\n", ai.Filename, bi.Filename) // differs only in case
+			}
+			if ai.Hash != bi.Hash {
+				fmt.Printf("modified:    %s\n", bi.Filename)
+				showText(ai, bi)
+			}
+			printMetaChanges(bi.Filename, ai, bi)
+		}
+		return nil
+	},
+}
+
+// printMetaChanges prints the differences in compression and header fields between a and b.
+func printMetaChanges(name string, a, b *diffItem) {
+	if a.Compression != b.Compression {
+		fmt.Printf("compression: %s (%s -> %s)\n", name, a.Compression, b.Compression)
+	}
+	if a.Unknown1 != b.Unknown1 || a.Unknown2 != b.Unknown2 {
+		fmt.Printf("metadata:    %s (unknown fields %d,%d -> %d,%d)\n", name, a.Unknown1, a.Unknown2, b.Unknown1, b.Unknown2)
+	}
+}
+
+// decodeText converts text to UTF-8 for display: it is used as-is if it is valid UTF-8, and decoded as Windows-1252
+// (like filenames) otherwise.
+func decodeText(text []byte) string {
+	if utf8.Valid(text) {
+		return string(text)
+	}
+	return vol.EncodingWindows1252.Decode(text)
+}
+
+var diffFlags struct {
+	Text bool
+}
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffFlags.Text, "text", false, "show changes to text files (such as .cs and .mis scripts) as unified diffs, after decompression")
+}
@@ -1,44 +1,42 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/iambob314/vol"
 	"github.com/spf13/cobra"
 	"os"
-	"path/filepath"
 )
 
 var dumpCmd = &cobra.Command{
 	Use:  "dump volfile [files...]",
-	Long: "vol dump prints the contents of a .vol file to stdout (only specific files, or whole file if none specified)",
+	Long: "vol dump prints the contents of a .vol file (or a Tribes 2 .vl2 file, or a directory) to stdout (only specific\nfiles, or whole file if none specified)",
 	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		volFN, fns := args[0], args[1:]
 
-		var v vol.File
-		if data, err := os.ReadFile(volFN); err != nil {
-			return fmt.Errorf("could not read file %s: %w", volFN, err)
-		} else if err := v.Parse(data); err != nil {
-			return fmt.Errorf("could not parse file %s: %w", volFN, err)
+		a, err := openArchive(volFN)
+		if err != nil {
+			return err
 		}
+		defer a.Close()
 
 		var fnmatch FilenameSet
 		if len(fns) > 0 {
 			fnmatch = fns
 		}
 
-		for _, item := range v.Items {
-			fn := filepath.Clean(item.Filename)
+		for _, e := range a.List() {
+			fn := e.Name.Clean()
 			if !fnmatch.Match(fn) {
 				continue
 			}
 
-			content := item.Payload
-			switch item.Compression {
-			case vol.None:
-				// content is already correct
-			default:
-				content = []byte(fmt.Sprintf("(content is %s compressed; decompression unsupported at this time)", item.Compression))
+			content, err := a.Open(string(e.Name))
+			if errors.Is(err, vol.ErrUnsupportedCompression) {
+				content = []byte(fmt.Sprintf("(content is %s compressed; decompression unsupported at this time)", e.Compression))
+			} else if err != nil {
+				return fmt.Errorf("could not decompress %s: %w", fn, err)
 			}
 
 			if dumpFlags.Raw {
@@ -46,7 +44,7 @@ var dumpCmd = &cobra.Command{
 			}
 			_, _ = os.Stdout.Write(content)
 			if dumpFlags.Raw {
-				if l := len(item.Payload); l == 0 || (item.Payload[l-1] != '\r' && item.Payload[l-1] != '\n') {
+				if l := len(content); l == 0 || (content[l-1] != '\r' && content[l-1] != '\n') {
 					fmt.Println()
 					fmt.Println("(no newline at end of file)")
 				}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// volmetaFilename is the name of the sidecar file written by vol explode, describing how to rebuild the vol.
+const volmetaFilename = ".volmeta"
+
+// rawItemsDir is the directory (within an exploded vol) holding items whose names cannot be used as host paths.
+const rawItemsDir = ".volitems"
+
+// volmeta is the content of a .volmeta sidecar file (in JSON).
+type volmeta struct {
+	Format     string        `json:"format"`
+	VOLHeaders string        `json:"vol_headers,omitempty"` // hex
+	Encoding   string        `json:"encoding"`
+	Dedupe     bool          `json:"dedupe,omitempty"` // see vol.File.Dedupe
+	SHA256     string        `json:"sha256"`           // of the original vol
+	Items      []volmetaItem `json:"items"`
+
+	// Skeleton rebuilds the original vol exactly, if Store alone would not (e.g. due to padding).
+	Skeleton *vol.Skeleton `json:"skeleton,omitempty"`
+}
+
+type volmetaItem struct {
+	Name        string `json:"name"`
+	File        string `json:"file"` // path of the item's content in the exploded directory, '/' separated
+	Compression string `json:"compression"`
+	Raw         bool   `json:"raw,omitempty"` // File holds the compressed payload as-is, since it could not be decompressed
+	Unknown1    uint32 `json:"unknown1"`
+	Unknown2    uint32 `json:"unknown2"`
+	SHA256      string `json:"sha256"` // of File's content
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+var explodeCmd = &cobra.Command{
+	Use:  "explode volfile outdir",
+	Long: "vol explode unpacks every file in a .vol file, plus a " + volmetaFilename + " file recording everything else\n(order, compression, unknown header fields, format), so that vol implode can rebuild the exact same bytes",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volFN, outdir := args[0], args[1]
+
+		data, err := os.ReadFile(volFN)
+		if err != nil {
+			return fmt.Errorf("could not read file %s: %w", volFN, err)
+		}
+		var v vol.File
+		if err := v.Parse(data); err != nil {
+			return fmt.Errorf("could not parse file %s: %w", volFN, err)
+		}
+
+		meta := volmeta{
+			Format:   v.Format.String(),
+			Encoding: vol.DefaultEncoding.String(),
+			Dedupe:   v.Dedupe,
+			SHA256:   sha256Hex(data),
+		}
+		if v.Format == vol.FormatVOL {
+			meta.VOLHeaders = hex.EncodeToString(v.VOLHeaders)
+		}
+
+		// Only keep a skeleton if needed, since it is not human-readable
+		var stored vol.ByteBuffer
+		if err := v.Store(&stored); err != nil || !bytes.Equal(stored, data) {
+			if meta.Skeleton, err = v.Skeleton(data); err != nil {
+				return fmt.Errorf("could not record layout of %s: %w", volFN, err)
+			}
+		}
+
+		usedFiles := make(map[string]bool) // case-folded host paths used so far
+		for i, item := range v.Items {
+			content, err := item.Decompress()
+			raw := errors.Is(err, vol.ErrUnsupportedCompression)
+			if raw {
+				content = item.Payload
+			} else if err != nil {
+				return fmt.Errorf("could not decompress %s: %w", item.Filename, err)
+			}
+
+			// Use the item's name as its path, unless it is unsafe or collides with another (e.g. by case)
+			name := vol.Path(item.Filename).Clean()
+			file := filepath.ToSlash(name.HostPath())
+			if name.CheckSafe() != nil || usedFiles[name.Key(false)] || name.Key(false) == volmetaFilename || name.Elems()[0] == rawItemsDir {
+				file = rawItemsDir + "/" + strconv.Itoa(i)
+			}
+			usedFiles[vol.Path(file).Key(false)] = true
+
+			fnFull := filepath.Join(outdir, filepath.FromSlash(file))
+			if err := os.MkdirAll(filepath.Dir(fnFull), 0777); err != nil {
+				return fmt.Errorf("could not create directory path %s: %w", filepath.Dir(fnFull), err)
+			} else if err := os.WriteFile(fnFull, content, 0666); err != nil {
+				return fmt.Errorf("could not create file %s: %w", fnFull, err)
+			}
+			fmt.Printf("exploded %s to %s\n", item.Filename, fnFull)
+
+			meta.Items = append(meta.Items, volmetaItem{
+				Name:        item.Filename,
+				File:        file,
+				Compression: item.Compression.String(),
+				Raw:         raw,
+				Unknown1:    item.Unknown1,
+				Unknown2:    item.Unknown2,
+				SHA256:      sha256Hex(content),
+			})
+		}
+
+		metaData, err := json.MarshalIndent(&meta, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(outdir, volmetaFilename), append(metaData, '\n'), 0666)
+	},
+}
+
+var implodeCmd = &cobra.Command{
+	Use:  "implode dir volfile",
+	Long: "vol implode rebuilds a .vol file from a directory written by vol explode. If no files were changed, the result\nis identical to the original vol.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, volFN := args[0], args[1]
+
+		metaFN := filepath.Join(dir, volmetaFilename)
+		metaData, err := os.ReadFile(metaFN)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", metaFN, err)
+		}
+		var meta volmeta
+		if err := json.Unmarshal(metaData, &meta); err != nil {
+			return fmt.Errorf("could not parse %s: %w", metaFN, err)
+		}
+
+		var v vol.File
+		v.CaseSensitive = true // names come from an existing vol; keep them exactly
+		v.Dedupe = meta.Dedupe
+		if v.Format, err = vol.ParseFormat(meta.Format); err != nil {
+			return err
+		} else if v.VOLHeaders, err = hex.DecodeString(meta.VOLHeaders); err != nil {
+			return fmt.Errorf("invalid vol_headers in %s: %w", metaFN, err)
+		} else if v.Encoding, err = vol.ParseEncoding(meta.Encoding); err != nil {
+			return err
+		}
+
+		unchanged, listed := true, make(map[string]bool)
+		for _, mi := range meta.Items {
+			fn, err := vol.Path(mi.File).JoinHost(dir)
+			if err != nil {
+				return fmt.Errorf("item %s: invalid file in %s: %w", mi.Name, metaFN, err)
+			}
+			listed[filepath.Clean(fn)] = true
+
+			content, err := os.ReadFile(fn)
+			if err != nil {
+				return fmt.Errorf("could not read input file %s: %w", fn, err)
+			}
+			if sha256Hex(content) != mi.SHA256 {
+				fmt.Printf("%s changed\n", fn)
+				unchanged = false
+			}
+
+			compression, err := vol.ParseCompressionType(mi.Compression)
+			if err != nil {
+				return fmt.Errorf("item %s: %w", mi.Name, err)
+			}
+			item := vol.Item{Filename: mi.Name, Compression: vol.None, Payload: content, Unknown1: mi.Unknown1, Unknown2: mi.Unknown2}
+			if mi.Raw {
+				item.Compression = compression
+			} else if err := item.Recompress(compression); err != nil {
+				return fmt.Errorf("could not compress %s: %w", fn, err)
+			}
+			if err := v.Add(item); err != nil {
+				return fmt.Errorf("could not pack %s: %w", fn, err)
+			}
+		}
+
+		// Files added to the directory are not packed, since they have no metadata; say so rather than silently ignore
+		_ = filepath.WalkDir(dir, func(fn string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && !listed[filepath.Clean(fn)] && filepath.Clean(fn) != metaFN {
+				fmt.Printf("ignoring %s (not listed in %s)\n", fn, metaFN)
+			}
+			return nil
+		})
+
+		if meta.Skeleton != nil && unchanged {
+			data, err := meta.Skeleton.Rebuild(v.Items)
+			if err != nil {
+				return fmt.Errorf("could not rebuild %s from %s: %w", volFN, metaFN, err)
+			}
+			// The skeleton holds the original names, compression, etc.; use it only if the metadata was not edited
+			rebuilt := vol.File{CaseSensitive: true, Encoding: v.Encoding}
+			if err := rebuilt.Parse(data); err == nil && sameVol(&rebuilt, &v) {
+				return vol.WriteFileFrom(volFN, bytes.NewReader(data))
+			}
+			fmt.Printf("%s changed; original layout (padding, etc.) cannot be kept, so the vol is rewritten in standard layout\n", metaFN)
+		} else if meta.Skeleton != nil {
+			fmt.Println("files changed; original layout (padding, etc.) cannot be kept, so the vol is rewritten in standard layout")
+		}
+		return vol.WriteFile(volFN, &v)
+	},
+}
+
+// sameVol reports whether a and b have the same format, headers and items (names, compression, header fields and
+// payloads, in order).
+func sameVol(a, b *vol.File) bool {
+	if a.Format != b.Format || !bytes.Equal(a.VOLHeaders, b.VOLHeaders) || a.Dedupe != b.Dedupe || len(a.Items) != len(b.Items) {
+		return false
+	}
+	for i := range a.Items {
+		ai, bi := &a.Items[i], &b.Items[i]
+		if ai.Filename != bi.Filename || ai.Compression != bi.Compression || ai.Unknown1 != bi.Unknown1 ||
+			ai.Unknown2 != bi.Unknown2 || !bytes.Equal(ai.Payload, bi.Payload) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,17 +1,32 @@
 package main
 
-import "path/filepath"
+import (
+	"github.com/iambob314/vol"
+	"path/filepath"
+	"strings"
+)
 
+// FilenameSet is a set of shell patterns matched against vol filenames (see vol.Path.Match); a nil set matches all.
+// Matching ignores case unless --case-sensitive.
 type FilenameSet []string
 
-func (fs FilenameSet) Match(fn string) bool {
+func (fs FilenameSet) Match(fn vol.Path) bool {
 	if fs == nil {
 		return true
 	}
 	for _, matcher := range fs {
-		if matched, _ := filepath.Match(matcher, fn); matched {
+		if rootFlags.CaseSensitive && fn.MatchCase(matcher) || !rootFlags.CaseSensitive && fn.Match(matcher) {
 			return true
 		}
 	}
 	return false
 }
+
+// hostPattern converts a glob pattern for host files, which may use '\' as a path separator as vol filenames do, to
+// the host's syntax, so that the same pattern works on every OS.
+func hostPattern(pattern string) string {
+	if filepath.Separator == vol.PathSeparator {
+		return pattern
+	}
+	return filepath.FromSlash(strings.ReplaceAll(pattern, string(vol.PathSeparator), "/"))
+}
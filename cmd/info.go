@@ -4,28 +4,39 @@ import (
 	"fmt"
 	"github.com/iambob314/vol"
 	"github.com/spf13/cobra"
-	"io/ioutil"
 )
 
 var infoCmd = &cobra.Command{
 	Use:  "info volfile [volfile ...]",
-	Long: "vol info summarizes the contents of a .vol file",
+	Long: "vol info summarizes the contents of a .vol file (or a Tribes 2 .vl2 file, or a directory)",
 	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		var strictErr error
 		for _, fn := range args {
-			var v vol.File
+			a, err := openArchive(fn)
+			if err != nil {
+				return err
+			}
 
-			if data, err := ioutil.ReadFile(fn); err != nil {
-				return fmt.Errorf("could not read file %s: %w", fn, err)
-			} else if err := v.Parse(data); err != nil {
-				return fmt.Errorf("could not parse file %s: %w", fn, err)
+			sharedWith := make(map[int]string) // item index -> name of the first item sharing its payload
+			if v, ok := a.(*vol.VolArchive); ok {
+				for _, group := range v.SharedPayloads() {
+					for _, i := range group[1:] {
+						sharedWith[i] = v.Items[group[0]].Filename
+					}
+				}
 			}
 
-			fmt.Printf("%s contains %d files:\n", fn, len(v.Items))
-			for _, item := range v.Items {
-				fmt.Printf("%s:\t%d bytes\t(compression: %s)\n", item.Filename, len(item.Payload), item.Compression)
+			entries := a.List()
+			fmt.Printf("%s contains %d files:\n", fn, len(entries))
+			for i, e := range entries {
+				if first, ok := sharedWith[i]; ok {
+					fmt.Printf("%s:\t%d bytes\t(compression: %s)\t(shared with %s)\n", e.Name, e.StoredSize, e.Compression, first)
+				} else {
+					fmt.Printf("%s:\t%d bytes\t(compression: %s)\n", e.Name, e.StoredSize, e.Compression)
+				}
 
-				if item.Compression == vol.LZH {
+				if e.Compression == vol.LZH.String() {
 					//data := append(make([]byte, 4), item.Payload...)
 					//binary.LittleEndian.PutUint32(data, uint32(len(item.Payload)))
 					//
@@ -37,7 +48,20 @@ var infoCmd = &cobra.Command{
 					//fmt.Println(n, string(out))
 				}
 			}
+
+			if err := checkStrictNames(infoFlags.StrictNames, entryNames(entries)); err != nil {
+				strictErr = fmt.Errorf("%s: %w", fn, err)
+			}
+			_ = a.Close()
 		}
-		return nil
+		return strictErr
 	},
 }
+
+var infoFlags struct {
+	StrictNames string
+}
+
+func init() {
+	addStrictNamesFlag(infoCmd, &infoFlags.StrictNames)
+}
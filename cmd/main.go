@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/iambob314/vol"
 	"github.com/spf13/cobra"
+	"os"
 )
 
 // const unsigned int DecodedLength(unsigned char *in);
@@ -19,15 +21,54 @@ var rootCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("must specify a subcommand")
 	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		enc, err := vol.ParseEncoding(rootFlags.Encoding)
+		if err != nil {
+			return err
+		}
+		vol.DefaultEncoding = enc
+		vol.DefaultLimits = rootFlags.Limits
+		return nil
+	},
+}
+
+var rootFlags struct {
+	Limits        vol.Limits
+	CaseSensitive bool
+	Encoding      string
 }
 
 func init() {
+	rootFlags.Limits = vol.DefaultLimits
+	rootCmd.PersistentFlags().IntVar(&rootFlags.Limits.MaxItems, "max-items", rootFlags.Limits.MaxItems, "refuse to read vols with more than this many files (0 = no limit)")
+	rootCmd.PersistentFlags().Int64Var(&rootFlags.Limits.MaxItemSize, "max-item-size", rootFlags.Limits.MaxItemSize, "refuse to read vols containing a file larger than this many bytes\nwhen decompressed (0 = no limit)")
+	rootCmd.PersistentFlags().Int64Var(&rootFlags.Limits.MaxTotalSize, "max-total-size", rootFlags.Limits.MaxTotalSize, "refuse to read vols whose files total more than this many bytes\nwhen decompressed (0 = no limit)")
+	rootCmd.PersistentFlags().BoolVar(&rootFlags.CaseSensitive, "case-sensitive", false, "treat filenames in vols that differ only in case as different files\n(by default they are the same file, as in the game)")
+	rootCmd.PersistentFlags().StringVar(&rootFlags.Encoding, "encoding", vol.DefaultEncoding.String(), "encoding of filenames in vols: windows-1252, or raw to use filename bytes as-is")
+	rootCmd.PersistentFlags().IntVar(&rootFlags.Limits.MaxFilenameLen, "max-filename-len", rootFlags.Limits.MaxFilenameLen, "refuse to read vols containing a filename longer than this many bytes\n(0 = no limit)")
+
 	rootCmd.AddCommand(infoCmd)
 	rootCmd.AddCommand(unpackCmd)
 	rootCmd.AddCommand(packCmd)
 	rootCmd.AddCommand(dumpCmd)
+	rootCmd.AddCommand(mvCmd)
+	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(manifestCmd)
+	rootCmd.AddCommand(explodeCmd)
+	rootCmd.AddCommand(implodeCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(textconvCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(mergeCmd)
+	rootCmd.AddCommand(splitCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
 }
 
 func main() {
-	rootCmd.ExecuteContext(context.Background())
+	if err := rootCmd.ExecuteContext(context.Background()); err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:  "merge out.vol base.vol [patch.vol...]",
+	Long: "vol merge combines .vol files into one. Files in later vols override files with the same name (ignoring case\nunless --case-sensitive) in earlier ones, in place; other files are added in order. Every overridden file is listed.\nFile contents are copied as-is, without recompressing.",
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outFN, inFNs := args[0], args[1:]
+		if mergeFlags.KeepFirst && mergeFlags.FailOnConflict {
+			return fmt.Errorf("--keep-first and --fail-on-conflict cannot be used together")
+		}
+
+		var (
+			out       vol.File
+			sources   = make(map[string]string) // name key -> vol the item in out came from
+			conflicts int
+		)
+		out.CaseSensitive = rootFlags.CaseSensitive
+		for i, fn := range inFNs {
+			v, err := vol.OpenMmap(fn)
+			if err != nil {
+				return fmt.Errorf("could not open file %s: %w", fn, err)
+			}
+			defer v.Close() // payloads are used until out is written
+
+			if i == 0 {
+				out.Encoding, out.Dedupe = v.Encoding, v.Dedupe // but not Format (see vol.File.VOLHeaders)
+			}
+
+			for _, item := range v.Items {
+				key := vol.Path(item.Filename).Key(rootFlags.CaseSensitive)
+				if !out.Has(item.Filename) {
+					sources[key] = fn
+					if err := out.Add(item); err != nil {
+						return fmt.Errorf("could not add %s from %s: %w", item.Filename, fn, err)
+					}
+					continue
+				}
+
+				conflicts++
+				switch {
+				case mergeFlags.FailOnConflict:
+					fmt.Printf("conflict: %s in %s and %s\n", item.Filename, sources[key], fn)
+				case mergeFlags.KeepFirst:
+					fmt.Printf("keeping %s from %s (over %s)\n", item.Filename, sources[key], fn)
+				default:
+					fmt.Printf("overriding %s from %s with %s\n", item.Filename, sources[key], fn)
+					sources[key] = fn
+					if err := out.Replace(item); err != nil {
+						return fmt.Errorf("could not replace %s from %s: %w", item.Filename, fn, err)
+					}
+				}
+			}
+		}
+
+		if mergeFlags.FailOnConflict && conflicts > 0 {
+			return fmt.Errorf("%d file(s) are in more than one vol; %s not written", conflicts, outFN)
+		}
+		return vol.WriteFile(outFN, &out)
+	},
+}
+
+var mergeFlags struct {
+	KeepFirst      bool
+	FailOnConflict bool
+}
+
+func init() {
+	mergeCmd.Flags().BoolVar(&mergeFlags.KeepFirst, "keep-first", false, "files in earlier vols take precedence over later ones")
+	mergeCmd.Flags().BoolVar(&mergeFlags.FailOnConflict, "fail-on-conflict", false, "fail, without writing out.vol, if any file is in more than one vol")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+)
+
+var mvCmd = &cobra.Command{
+	Use:  "mv volfile old new\n  vol mv volfile --sed s/pattern/replacement/[gi]",
+	Long: "vol mv renames files within a .vol file, without unpacking or recompressing them",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if mvFlags.Sed != "" {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(3)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volFN := args[0]
+
+		err := vol.Edit(volFN, func(tx *vol.Tx) error {
+			tx.CaseSensitive = rootFlags.CaseSensitive
+			tx.Reindex()
+
+			if mvFlags.Sed == "" {
+				// Names are canonicalized as in pack; the old name is also looked up as given, in case the vol holds a
+				// name that is not canonical
+				oldName, newName := args[1], string(vol.Path(args[2]).Clean())
+				if !tx.Has(oldName) {
+					oldName = string(vol.Path(oldName).Clean())
+				}
+				if err := tx.Rename(oldName, newName); err != nil {
+					return fmt.Errorf("cannot rename %s to %s in vol file %s: %w", oldName, newName, volFN, err)
+				}
+				fmt.Printf("renaming %s to %s\n", oldName, newName)
+				return nil
+			}
+
+			sed, err := parseSedExpr(mvFlags.Sed)
+			if err != nil {
+				return err
+			}
+
+			// Rebuild the item list under the new names, so that collisions between new names (and names of items
+			// that are not renamed) are detected before anything is printed or written
+			var (
+				items    []vol.Item
+				renames  []string              // "renaming" messages, printed once there are no collisions
+				sourceOf = map[string]string{} // name key (see vol.Path.Key) -> original name of the item with that name
+			)
+			for _, it := range tx.Items {
+				oldName := it.Filename
+				newName, matched := sed.Apply(oldName)
+				if matched {
+					newName = string(vol.Path(newName).Clean())
+				}
+				if matched && newName != oldName {
+					renames = append(renames, fmt.Sprintf("renaming %s to %s", oldName, newName))
+					it.Filename = newName
+				}
+				key := vol.Path(it.Filename).Key(tx.CaseSensitive)
+				if src, ok := sourceOf[key]; ok {
+					return fmt.Errorf("cannot rename: %s and %s would both be named %s: %w", src, oldName, it.Filename, vol.ErrExists)
+				}
+				sourceOf[key] = oldName
+				items = append(items, it)
+			}
+			for _, msg := range renames {
+				fmt.Println(msg)
+			}
+			numRenamed := len(renames)
+			if numRenamed == 0 {
+				return errNoFilesRenamed
+			}
+			tx.Items = items
+			tx.Reindex()
+			return nil
+		})
+		if errors.Is(err, errNoFilesRenamed) {
+			fmt.Println(err)
+			return nil
+		}
+		return err
+	},
+}
+
+var errNoFilesRenamed = errors.New("no files renamed")
+
+var mvFlags struct {
+	Sed string
+}
+
+func init() {
+	mvCmd.Flags().StringVar(&mvFlags.Sed, "sed", "", "rename all files matching a sed-style substitution s/pattern/replacement/[flags]\n(pattern is a Go regexp; \\1..\\9 and & in replacement refer to submatches;\nflags: g = replace all matches, i = ignore case)")
+}
@@ -6,34 +6,20 @@ import (
 	"github.com/spf13/cobra"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 var packCmd = &cobra.Command{
 	Use:  "pack volfile [file...]",
-	Long: "vol pack packs files into a new or existing .vol file",
+	Long: "vol pack packs files into a new or existing .vol file (or .vl2 file, or directory); with -r, directories are packed recursively",
 	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		volFN, fns := args[0], args[1:]
 
-		var v vol.File
-		if data, err := os.ReadFile(volFN); os.IsNotExist(err) { // file does not exist
-			// nothing to do; leave v empty
-		} else if err != nil { // file could not be read
-			return fmt.Errorf("could not read vol file %s: %w", volFN, err)
-		} else if err := v.Parse(data); err != nil { // file could not be parsed
-			return fmt.Errorf("could not parse vol file %s: %w", volFN, err)
-		}
-
-		// Keep a table of filename-to-itemidx for the vol file, so we can error or overwrite on duplicate
-		filesInVol := make(map[string]int, len(v.Items))
-		for i, it := range v.Items {
-			filesInVol[filepath.Clean(it.Filename)] = i
-		}
-
 		// Expand fileglobs (for Windows, which does not do this in the shell...)
 		var expandedFNs []string
 		for _, fn := range fns {
-			if expanded, err := filepath.Glob(fn); err != nil {
+			if expanded, err := filepath.Glob(hostPattern(fn)); err != nil {
 				return fmt.Errorf("invalid filename or glob pattern %s: %w", fn, err)
 			} else if expanded != nil {
 				expandedFNs = append(expandedFNs, expanded...)
@@ -41,60 +27,155 @@ var packCmd = &cobra.Command{
 		}
 		fns = expandedFNs
 
-		// Load and append all files as vol items (overwriting existing items where needed/allowed)
+		// Expand directories (if recursive) into the files within them
+		expandedFNs = nil
+		for _, fn := range fns {
+			if stat, err := os.Stat(fn); err != nil {
+				return fmt.Errorf("could not access input file %s: %w", fn, err)
+			} else if !stat.IsDir() {
+				expandedFNs = append(expandedFNs, fn)
+			} else if !packFlags.Recursive {
+				return fmt.Errorf("input file %s is a directory; use -r to pack directories recursively", fn)
+			} else if walked, err := walkPackDir(fn); err != nil {
+				return fmt.Errorf("could not walk directory %s: %w", fn, err)
+			} else {
+				expandedFNs = append(expandedFNs, walked...)
+			}
+		}
+		fns = expandedFNs
+
+		filter := packFilter{Include: packFlags.Include, Exclude: packFlags.Exclude}
+		order, err := vol.ParseSortOrder(packFlags.Sort)
+		if err != nil {
+			return err
+		}
+
+		// Load and append all files to the archive (overwriting existing files where needed/allowed)
+		a, err := vol.CreateArchive(volFN, rootFlags.CaseSensitive)
+		if err != nil {
+			return fmt.Errorf("could not open %s: %w", volFN, err)
+		}
+		defer a.Close()
+
+		v, isVol := a.(*vol.VolArchive)
+		if isVol {
+			v.Dedupe = v.Dedupe || packFlags.Dedupe
+		} else if order != vol.SortNone || packFlags.Dedupe {
+			return fmt.Errorf("--sort and --dedupe are only supported when packing into a vol file")
+		}
+
+		// Read every file and check every name before writing anything, as a directory is written to by each Write
+		type packFile struct {
+			HostPath  string
+			Name      vol.Path
+			Data      []byte
+			Overwrite bool
+		}
+		var (
+			files   []packFile
+			newKeys = make(map[string]bool) // name keys (see vol.Path.Key) of files not yet in the archive
+			names   = entryNames(a.List())  // names in the archive once packed, for --strict-names
+		)
+		_, isDir := a.(*vol.DirArchive)
 		for _, fn := range fns {
 			fn = filepath.Clean(fn)
 
-			fnInPack := fn
+			fnInPack, err := packName(fn)
+			if err != nil {
+				return err
+			} else if !filter.Match(fnInPack) {
+				continue
+			}
 			if packFlags.StripPaths {
-				fnInPack = filepath.Base(fn)
+				fnInPack = fnInPack.Base()
 			}
-
-			if data, err := os.ReadFile(fn); err != nil {
-				return fmt.Errorf("could not read input file %s: %w", fn, err)
-			} else if idxInVol, overwrite := filesInVol[fnInPack]; overwrite && !packFlags.Overwrite {
-				return fmt.Errorf("file %s already exists in vol file %s; use --overwrite to overwrite", fnInPack, volFN)
-			} else {
-				newItem := vol.Item{
-					Compression: vol.None,
-					Filename:    fnInPack,
-					Payload:     data,
-				}
-
-				msg := "packing " + fn
-				if fn != fnInPack {
-					msg += " (as " + fnInPack + ")"
-				}
-				if overwrite {
-					msg += " (overwrite)"
+			if isDir {
+				if err := fnInPack.CheckSafe(); err != nil {
+					return fmt.Errorf("cannot pack %s into directory %s: %w", fn, volFN, err)
 				}
-				fmt.Println(msg)
+			}
 
-				if overwrite {
-					v.Items[idxInVol] = newItem
-				} else {
-					filesInVol[fnInPack] = len(v.Items)
-					v.Items = append(v.Items, newItem)
-				}
+			data, err := os.ReadFile(fn)
+			if err != nil {
+				return fmt.Errorf("could not read input file %s: %w", fn, err)
 			}
+			key := fnInPack.Key(rootFlags.CaseSensitive)
+			_, err = a.Stat(string(fnInPack))
+			overwrite := err == nil || newKeys[key]
+			if overwrite && !packFlags.Overwrite {
+				return fmt.Errorf("file %s already exists in %s; use --overwrite to overwrite", fnInPack, volFN)
+			} else if !overwrite {
+				newKeys[key] = true
+				names = append(names, string(fnInPack))
+			}
+			files = append(files, packFile{HostPath: fn, Name: fnInPack, Data: data, Overwrite: overwrite})
+		}
+		if err := checkStrictNames(packFlags.StrictNames, names); err != nil {
+			return err
 		}
 
-		var newVolData vol.ByteBuffer
-		v.Store(&newVolData)
+		for _, f := range files {
+			msg := "packing " + f.HostPath
+			if f.HostPath != f.Name.HostPath() {
+				msg += " (as " + string(f.Name) + ")"
+			}
+			if f.Overwrite {
+				msg += " (overwrite)"
+			}
+			fmt.Println(msg)
 
-		if err := os.WriteFile(volFN, newVolData, 0666); err != nil {
-			return fmt.Errorf("could not create or overwrite vol file %s: %w", volFN, err)
+			if err := a.Write(string(f.Name), f.Data); err != nil {
+				return fmt.Errorf("could not pack %s into %s: %w", f.HostPath, volFN, err)
+			}
 		}
-		return nil
+		if isVol {
+			v.Sort(order)
+			v.Reindex()
+		}
+		return a.Commit()
 	},
 }
 
+// packName returns the name in the vol for host file fn, relative to --base (if given).
+func packName(fn string) (vol.Path, error) {
+	if packFlags.Base != "" {
+		absBase, err := filepath.Abs(packFlags.Base)
+		if err != nil {
+			return "", err
+		}
+		absFN, err := filepath.Abs(fn)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(absBase, absFN)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("input file %s is not within --base directory %s", fn, packFlags.Base)
+		}
+		fn = rel
+	}
+	return vol.PathFromHost(fn), nil
+}
+
 var packFlags struct {
-	StripPaths bool
-	Overwrite  bool
+	StripPaths  bool
+	Overwrite   bool
+	StrictNames string
+	Recursive   bool
+	Include     []string
+	Exclude     []string
+	Base        string
+	Sort        string
+	Dedupe      bool
 }
 
 func init() {
 	packCmd.Flags().BoolVar(&packFlags.StripPaths, "strip-paths", false, "remove file paths when packing files into the vol; keep only filenames")
 	packCmd.Flags().BoolVar(&packFlags.Overwrite, "overwrite", false, "allow overwriting files when packing into an existing vol; if absent, error on attempted overwrite")
+	addStrictNamesFlag(packCmd, &packFlags.StrictNames)
+	packCmd.Flags().BoolVarP(&packFlags.Recursive, "recursive", "r", false, "pack directories recursively, skipping files matched by .volignore files\n(one pattern per line, like .gitignore)")
+	packCmd.Flags().StringArrayVar(&packFlags.Include, "include", nil, "pack only files whose names in the vol match this pattern (repeatable);\npatterns may contain ** to match any number of directories, and patterns\nwithout a path separator match the filename in any directory")
+	packCmd.Flags().StringArrayVar(&packFlags.Exclude, "exclude", nil, "do not pack files whose names in the vol match this pattern (repeatable;\nsame syntax as --include)")
+	packCmd.Flags().StringVar(&packFlags.Base, "base", "", "name files in the vol by their paths relative to this directory\n(by default, by their paths as given)")
+	packCmd.Flags().BoolVar(&packFlags.Dedupe, "dedupe", false, "store identical file contents only once, shared by all files with them\n(vols that are already deduped stay so)")
+	packCmd.Flags().StringVar(&packFlags.Sort, "sort", vol.SortNone.String(), "order of files in the vol: none (existing files first, then in the order\ngiven), name (ignoring case), or size (smallest first)")
 }
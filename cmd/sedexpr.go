@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sedExpr is a parsed sed-style substitution expression: s/pattern/replacement/[flags]
+type sedExpr struct {
+	re     *regexp.Regexp
+	repl   string
+	global bool
+}
+
+// parseSedExpr parses a sed-style substitution expression of the form s<d>pattern<d>replacement<d>[flags], where <d> is
+// any delimiter character (usually '/'). pattern is a Go regexp. In replacement, \1 thru \9 refer to submatches and &
+// to the whole match, as in sed. Supported flags are g (replace all matches, not just the first) and i (ignore case).
+func parseSedExpr(expr string) (*sedExpr, error) {
+	if len(expr) < 2 || expr[0] != 's' {
+		return nil, fmt.Errorf("invalid substitution %q: must be of the form s/pattern/replacement/[flags]", expr)
+	}
+
+	delim := expr[1]
+	parts := splitUnescaped(expr[2:], delim)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid substitution %q: must be of the form s/pattern/replacement/[flags]", expr)
+	}
+	pattern, repl, flags := parts[0], parts[1], parts[2]
+
+	var sed sedExpr
+	for _, f := range flags {
+		switch f {
+		case 'g':
+			sed.global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return nil, fmt.Errorf("invalid substitution %q: unknown flag %c", expr, f)
+		}
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid substitution %q: %w", expr, err)
+	}
+	sed.re, sed.repl = re, convertSedReplacement(repl)
+	return &sed, nil
+}
+
+// Apply applies the substitution to s, returning the result and whether the pattern matched at all.
+func (e *sedExpr) Apply(s string) (string, bool) {
+	if e.global {
+		if !e.re.MatchString(s) {
+			return s, false
+		}
+		return e.re.ReplaceAllString(s, e.repl), true
+	}
+
+	loc := e.re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return s, false
+	}
+	var dst []byte
+	dst = e.re.ExpandString(dst, e.repl, s, loc)
+	return s[:loc[0]] + string(dst) + s[loc[1]:], true
+}
+
+// splitUnescaped splits s on each occurrence of delim not preceded by a backslash. In the first part (the pattern), a
+// backslash-escaped delimiter is replaced with a regexp matching it literally, even if it is a regexp metacharacter
+// such as '|'; in later parts, it is kept escaped, which convertSedReplacement turns into the literal delimiter. Other
+// backslash escapes are kept as-is (they are meaningful to the regexp or replacement).
+func splitUnescaped(s string, delim byte) []string {
+	var (
+		parts []string
+		cur   strings.Builder
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && s[i+1] == delim && len(parts) == 0:
+			cur.WriteString(regexp.QuoteMeta(string(delim)))
+			i++
+		case c == '\\' && i+1 < len(s):
+			cur.WriteByte(c)
+			cur.WriteByte(s[i+1])
+			i++
+		case c == delim:
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	return append(parts, cur.String())
+}
+
+// convertSedReplacement converts a sed replacement string (\1, &, \&, \\) to regexp.Expand syntax (${1}, ${0}, $$).
+func convertSedReplacement(repl string) string {
+	var sb strings.Builder
+	for i := 0; i < len(repl); i++ {
+		switch c := repl[i]; {
+		case c == '\\' && i+1 < len(repl):
+			i++
+			if n := repl[i]; n >= '0' && n <= '9' {
+				sb.WriteString("${" + string(n) + "}")
+			} else if n == '$' {
+				sb.WriteString("$$")
+			} else {
+				sb.WriteByte(n)
+			}
+		case c == '&':
+			sb.WriteString("${0}")
+		case c == '$':
+			sb.WriteString("$$")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
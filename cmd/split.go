@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseSize parses a size in bytes, with an optional suffix: KB, MB or GB (powers of 1000), or KiB, MiB or GiB (powers
+// of 1024). Suffixes ignore case.
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		Suffix string
+		Mult   int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"B", 1},
+	}
+	num, mult := strings.TrimSpace(s), int64(1)
+	for _, u := range units {
+		if len(num) > len(u.Suffix) && strings.EqualFold(num[len(num)-len(u.Suffix):], u.Suffix) {
+			num, mult = strings.TrimSpace(num[:len(num)-len(u.Suffix)]), u.Mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 50MB or 1048576)", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// splitGroup is a set of items (indices into the vol being split) in the same directory, kept in the same output vol
+// if possible.
+type splitGroup struct {
+	Items []int
+	Size  int64 // bytes the items add to a vol
+}
+
+// splitBin is an output vol being filled by splitItems.
+type splitBin struct {
+	Items []int
+	Size  int64
+}
+
+// splitItems assigns items, with the given sizes (the bytes each adds to a vol), to bins of at most capacity bytes,
+// keeping items in the same directory together where possible. Groups are placed largest first, each into the first
+// bin with room (first-fit decreasing); a group too large for any bin is split into its items, placed the same way.
+// Each bin's items are returned in their original order. Every item must fit in a bin by itself.
+func splitItems(items []vol.Item, sizes []int64, capacity int64) [][]int {
+	var (
+		groups []*splitGroup
+		byDir  = make(map[string]*splitGroup)
+	)
+	for i, item := range items {
+		dir := vol.Path(item.Filename).Dir().Key(rootFlags.CaseSensitive)
+		g, ok := byDir[dir]
+		if !ok {
+			g = &splitGroup{}
+			byDir[dir] = g
+			groups = append(groups, g)
+		}
+		g.Items = append(g.Items, i)
+		g.Size += sizes[i]
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Size > groups[j].Size })
+
+	var bins []*splitBin
+	place := func(items []int, size int64) {
+		for _, b := range bins {
+			if b.Size+size <= capacity {
+				b.Items, b.Size = append(b.Items, items...), b.Size+size
+				return
+			}
+		}
+		bins = append(bins, &splitBin{Items: append([]int(nil), items...), Size: size})
+	}
+	for _, g := range groups {
+		if g.Size <= capacity {
+			place(g.Items, g.Size)
+			continue
+		}
+		split := append([]int(nil), g.Items...)
+		sort.SliceStable(split, func(i, j int) bool { return sizes[split[i]] > sizes[split[j]] })
+		for _, i := range split {
+			place([]int{i}, sizes[i])
+		}
+	}
+
+	out := make([][]int, len(bins))
+	for i, b := range bins {
+		sort.Ints(b.Items)
+		out[i] = b.Items
+	}
+	return out
+}
+
+var splitCmd = &cobra.Command{
+	Use:  "split volfile --max-size SIZE out_%02d.vol",
+	Long: "vol split splits a .vol file into several, each at most --max-size bytes (e.g. 50MB or 10MiB). Files in the same\ndirectory are kept in the same vol where possible. Output vols are named by formatting the pattern with their number\n(from 1), and an index listing which vol holds each file is written next to them.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volFN, pattern := args[0], args[1]
+		if first := fmt.Sprintf(pattern, 1); strings.Contains(first, "%!") || first == fmt.Sprintf(pattern, 2) {
+			return fmt.Errorf("output pattern %s must contain a number format, such as %%02d", pattern)
+		} else if splitFlags.MaxSize == "" {
+			return fmt.Errorf("--max-size is required")
+		}
+		maxSize, err := parseSize(splitFlags.MaxSize)
+		if err != nil {
+			return err
+		}
+
+		v, err := vol.OpenMmap(volFN)
+		if err != nil {
+			return fmt.Errorf("could not open file %s: %w", volFN, err)
+		}
+		defer v.Close()
+
+		// Every vol has a fixed overhead (headers), plus the size of each item (block, name and item header)
+		newVol := func() vol.File {
+			return vol.File{Encoding: v.Encoding, CaseSensitive: true} // always PVOL (see vol.File.VOLHeaders)
+		}
+		empty := newVol()
+		overhead, err := empty.StoredLen()
+		if err != nil {
+			return err
+		}
+		sizes := make([]int64, len(v.Items))
+		for i, item := range v.Items {
+			single := newVol()
+			single.Items = []vol.Item{item}
+			size, err := single.StoredLen()
+			if err != nil {
+				return err
+			}
+			if size > maxSize {
+				return fmt.Errorf("%s does not fit in a vol of --max-size: a vol of only it is %d bytes", item.Filename, size)
+			}
+			sizes[i] = size - overhead
+		}
+
+		bins := splitItems(v.Items, sizes, maxSize-overhead)
+
+		var index strings.Builder
+		for n, bin := range bins {
+			outFN := fmt.Sprintf(pattern, n+1)
+			out := newVol()
+			for _, i := range bin {
+				out.Items = append(out.Items, v.Items[i])
+				fmt.Fprintf(&index, "%s\t%s\n", filepath.Base(outFN), v.Items[i].Filename)
+			}
+			if err := vol.WriteFile(outFN, &out); err != nil {
+				return err
+			}
+			size, _ := out.StoredLen()
+			fmt.Printf("wrote %s (%d files, %d bytes)\n", outFN, len(out.Items), size)
+		}
+
+		indexFN := splitFlags.Index
+		if indexFN == "" {
+			indexFN = filepath.Join(filepath.Dir(pattern), strings.TrimSuffix(filepath.Base(volFN), filepath.Ext(volFN))+".index.txt")
+		}
+		if err := os.WriteFile(indexFN, []byte(index.String()), 0666); err != nil {
+			return fmt.Errorf("could not write index %s: %w", indexFN, err)
+		}
+		fmt.Printf("wrote index %s\n", indexFN)
+		return nil
+	},
+}
+
+var splitFlags struct {
+	MaxSize string
+	Index   string
+}
+
+func init() {
+	splitCmd.Flags().StringVar(&splitFlags.MaxSize, "max-size", "", "maximum size of each output vol, in bytes or with a suffix: KB, MB, GB\n(powers of 1000) or KiB, MiB, GiB (powers of 1024)")
+	splitCmd.Flags().StringVar(&splitFlags.Index, "index", "", "write the index (one line per file: vol, tab, name) to this file\n(default: volfile's name with .index.txt, in the output directory)")
+}
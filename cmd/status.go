@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// treeStatus is the state of a file in a directory compared to a vol (see compareTree).
+type treeStatus byte
+
+const (
+	statusUnchanged = treeStatus(iota) // in both, with the same content
+	statusModified                     // in both, with different content
+	statusNew                          // only on disk
+	statusDeleted                      // only in the vol
+	statusUnknown                      // in the vol, but its content cannot be decompressed to compare (see itemContent)
+)
+
+func (s treeStatus) String() string {
+	switch s {
+	case statusUnchanged:
+		return "unchanged"
+	case statusModified:
+		return "modified"
+	case statusNew:
+		return "new"
+	case statusDeleted:
+		return "deleted"
+	case statusUnknown:
+		return "unknown"
+	default:
+		return fmt.Sprintf("treeStatus(%d)", byte(s))
+	}
+}
+
+// treeEntry is a file in a directory and/or a vol, as compared by compareTree.
+type treeEntry struct {
+	Name     vol.Path // name in the vol, if in the vol; else the name it would be packed as
+	HostPath string   // path on disk, if on disk
+	Status   treeStatus
+	Data     []byte // content on disk, if on disk
+}
+
+// compareTree compares the files in v with the files under host directory dir (as found by pack -r, so .volignore
+// files apply), matching vol names to paths relative to dir. Files in the vol are returned in vol order, followed by
+// new files in lexical order. Items whose content cannot be decompressed are reported as unknown, whether on disk or
+// not (unpack skips them, and explode writes their raw payloads), as they cannot be compared. If the vol file
+// itself (host path volFN) is within dir, it is skipped, along with its temporary files (see vol.WriteFile).
+func compareTree(v *vol.File, dir, volFN string) ([]treeEntry, error) {
+	walked, err := walkPackDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not walk directory %s: %w", dir, err)
+	}
+	var fns []string
+	for _, fn := range walked {
+		if !isVolOutput(fn, volFN) {
+			fns = append(fns, fn)
+		}
+	}
+
+	onDisk := make(map[string]string, len(fns)) // name key -> host path
+	var newEntries []treeEntry
+	for _, fn := range fns {
+		rel, err := filepath.Rel(dir, fn)
+		if err != nil {
+			return nil, err
+		}
+		name := vol.PathFromHost(rel)
+		if v.Has(string(name)) {
+			onDisk[name.Key(rootFlags.CaseSensitive)] = fn
+		} else {
+			newEntries = append(newEntries, treeEntry{Name: name, HostPath: fn, Status: statusNew})
+		}
+	}
+
+	entries := make([]treeEntry, 0, len(v.Items)+len(newEntries))
+	for i := range v.Items {
+		item := &v.Items[i]
+		name := vol.Path(item.Filename)
+		fn, ok := onDisk[name.Key(rootFlags.CaseSensitive)]
+		content, decompressed, err := itemContent(item)
+		if err != nil || !decompressed {
+			entries = append(entries, treeEntry{Name: name, HostPath: fn, Status: statusUnknown})
+			continue
+		} else if !ok {
+			entries = append(entries, treeEntry{Name: name, Status: statusDeleted})
+			continue
+		}
+
+		entry := treeEntry{Name: name, HostPath: fn, Status: statusModified}
+		if entry.Data, err = os.ReadFile(fn); err != nil {
+			return nil, fmt.Errorf("could not read input file %s: %w", fn, err)
+		}
+		if bytes.Equal(content, entry.Data) {
+			entry.Status = statusUnchanged
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, entry := range newEntries {
+		if entry.Data, err = os.ReadFile(entry.HostPath); err != nil {
+			return nil, fmt.Errorf("could not read input file %s: %w", entry.HostPath, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// isVolOutput reports whether host file fn is vol file volFN, or one of the temporary files written while replacing it.
+func isVolOutput(fn, volFN string) bool {
+	dir, _ := filepath.Abs(filepath.Dir(fn))
+	volDir, _ := filepath.Abs(filepath.Dir(volFN))
+	if dir != volDir {
+		return false
+	}
+	base, volBase := filepath.Base(fn), filepath.Base(volFN)
+	return base == volBase || strings.HasPrefix(base, "."+volBase+".tmp")
+}
+
+var statusCmd = &cobra.Command{
+	Use:  "status volfile dir",
+	Long: "vol status compares a .vol file to a directory (such as one it was unpacked to), listing files that are new on disk,\nmodified, or deleted from disk, like git status: that is, what vol pack -r would change. Files whose compression\ncannot be decompressed are listed as unknown, as they cannot be compared.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volFN, dir := args[0], args[1]
+
+		v, err := vol.OpenMmap(volFN)
+		if err != nil {
+			return fmt.Errorf("could not open file %s: %w", volFN, err)
+		}
+		defer v.Close()
+		v.CaseSensitive = rootFlags.CaseSensitive
+		v.Reindex()
+
+		entries, err := compareTree(&v.File, dir, volFN)
+		if err != nil {
+			return err
+		}
+
+		var counts [statusUnknown + 1]int
+		for _, entry := range entries {
+			counts[entry.Status]++
+			if entry.Status != statusUnchanged || statusFlags.All {
+				fmt.Printf("%-10s %s\n", entry.Status.String()+":", entry.Name)
+			}
+		}
+		fmt.Printf("%d new, %d modified, %d deleted, %d unchanged, %d unknown\n", counts[statusNew], counts[statusModified], counts[statusDeleted], counts[statusUnchanged], counts[statusUnknown])
+		return nil
+	},
+}
+
+var statusFlags struct {
+	All bool
+}
+
+func init() {
+	statusCmd.Flags().BoolVarP(&statusFlags.All, "all", "a", false, "also list unchanged files")
+}
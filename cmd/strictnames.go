@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+)
+
+// addStrictNamesFlag adds the --strict-names flag to cmd, storing the name of the selected vol.NameRules in target.
+func addStrictNamesFlag(cmd *cobra.Command, target *string) {
+	cmd.Flags().StringVar(target, "strict-names", "", "check filenames against the rules of the given game (tribes, or starsiege\nfor 8.3 names); --strict-names alone means tribes")
+	cmd.Flags().Lookup("strict-names").NoOptDefVal = vol.TribesNames.Name
+}
+
+// checkStrictNames checks names against the vol.NameRules named rulesName (if not empty), printing each violation. If
+// there are any, an error is returned.
+func checkStrictNames(rulesName string, names []string) error {
+	if rulesName == "" {
+		return nil
+	}
+	rules, err := vol.ParseNameRules(rulesName)
+	if err != nil {
+		return err
+	}
+
+	numInvalid := 0
+	for _, name := range names {
+		if err := rules.Check(name); err != nil {
+			fmt.Printf("%s: %v\n", rules.Name, err)
+			numInvalid++
+		}
+	}
+	if numInvalid > 0 {
+		return fmt.Errorf("%d filename(s) break the %s name rules", numInvalid, rules.Name)
+	}
+	return nil
+}
+
+// itemNames returns the filenames of items.
+func itemNames(items []vol.Item) []string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Filename
+	}
+	return names
+}
+
+// entryNames returns the names of entries.
+func entryNames(entries []vol.Entry) []string {
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = string(e.Name)
+	}
+	return names
+}
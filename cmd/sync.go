@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var syncCmd = &cobra.Command{
+	Use:  "sync dir volfile",
+	Long: "vol sync makes a .vol file match a directory tree: files new on disk are added, modified files are replaced (in\nplace), and files deleted from disk are removed. Unchanged files, and files whose compression cannot be decompressed,\nare left untouched. See also vol status.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, volFN := args[0], args[1]
+		return syncDir(dir, volFN, syncFlags.DryRun, syncFlags.StrictNames, syncFlags.SyncUnknown)
+	},
+}
+
+// syncDir makes vol file volFN match host directory dir (see vol sync), printing each change. If dryRun, the changes
+// are only printed. Files with names breaking the rules named strictNames (see --strict-names) are an error. Items
+// whose content cannot be compared (see statusUnknown) are kept as they are unless syncUnknown, in which case they are
+// replaced with the file on disk (as is), or removed if there is none.
+func syncDir(dir, volFN string, dryRun bool, strictNames string, syncUnknown bool) error {
+	err := vol.Edit(volFN, func(tx *vol.Tx) error {
+		tx.CaseSensitive = rootFlags.CaseSensitive
+		tx.Reindex()
+
+		entries, err := compareTree(tx.File, dir, volFN)
+		if err != nil {
+			return err
+		}
+
+		// Rebuild the item list in one pass, rather than removing items one at a time
+		var (
+			items      = make([]vol.Item, 0, len(entries))
+			numChanges int
+		)
+		for i, entry := range entries {
+			switch entry.Status {
+			case statusUnchanged:
+				items = append(items, tx.Items[i])
+			case statusModified:
+				item := tx.Items[i] // keep the name and header fields
+				item.Compression, item.Payload = vol.None, entry.Data
+				items = append(items, item)
+				fmt.Printf("packing %s (as %s) (overwrite)\n", entry.HostPath, entry.Name)
+			case statusNew:
+				items = append(items, vol.Item{Filename: string(entry.Name), Compression: vol.None, Payload: entry.Data})
+				fmt.Printf("packing %s (as %s)\n", entry.HostPath, entry.Name)
+			case statusDeleted:
+				fmt.Printf("removing %s\n", entry.Name)
+			case statusUnknown:
+				item := tx.Items[i]
+				if !syncUnknown {
+					items = append(items, item)
+					fmt.Printf("keeping %s (%s compressed, so it cannot be compared)\n", entry.Name, item.Compression)
+					continue
+				} else if entry.HostPath == "" {
+					fmt.Printf("removing %s\n", entry.Name)
+				} else if item.Payload, err = os.ReadFile(entry.HostPath); err != nil {
+					return fmt.Errorf("could not read input file %s: %w", entry.HostPath, err)
+				} else {
+					item.Compression = vol.None
+					items = append(items, item)
+					fmt.Printf("packing %s (as %s) (overwrite)\n", entry.HostPath, entry.Name)
+				}
+			}
+			if entry.Status != statusUnchanged {
+				numChanges++
+			}
+		}
+
+		if dryRun || numChanges == 0 {
+			return errNothingToSync
+		}
+		tx.Items = items
+		tx.Reindex()
+		return checkStrictNames(strictNames, itemNames(tx.Items))
+	})
+	if errors.Is(err, errNothingToSync) {
+		if dryRun {
+			fmt.Printf("dry run; %s not changed\n", volFN)
+		} else {
+			fmt.Printf("%s is up to date\n", volFN)
+		}
+		return nil
+	}
+	return err
+}
+
+// errNothingToSync aborts vol sync's edit without writing the vol.
+var errNothingToSync = errors.New("nothing to sync")
+
+var syncFlags struct {
+	DryRun      bool
+	StrictNames string
+	SyncUnknown bool
+}
+
+func init() {
+	syncCmd.Flags().BoolVarP(&syncFlags.DryRun, "dry-run", "n", false, "print the changes that would be made, but do not change the vol")
+	syncCmd.Flags().BoolVar(&syncFlags.SyncUnknown, "sync-unknown", false, "also replace files whose compression cannot be decompressed (LZH, etc.) with the file on disk\nas is, or remove them if not on disk; by default they are kept")
+	addStrictNamesFlag(syncCmd, &syncFlags.StrictNames)
+}
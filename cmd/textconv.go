@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"github.com/iambob314/vol"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var textconvCmd = &cobra.Command{
+	Use:  "textconv volfile",
+	Long: "vol textconv prints a stable, line-oriented rendering of a .vol file, for use as a git textconv driver: one line\nper file with its name, size, stored size, compression and SHA-256, in vol order. With --text, each text file's\ncontent (after decompression) follows its line, indented.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volFN := args[0]
+
+		v, err := vol.OpenMmap(volFN)
+		if err != nil {
+			return fmt.Errorf("could not open file %s: %w", volFN, err)
+		}
+		defer v.Close()
+
+		w := bufio.NewWriter(os.Stdout)
+		fmt.Fprintf(w, "# %s vol, %d files\n", v.Format, len(v.Items))
+		for i := range v.Items {
+			item := &v.Items[i]
+			content, decompressed, err := itemContent(item)
+			if err != nil {
+				return fmt.Errorf("could not decompress %s: %w", item.Filename, err)
+			}
+
+			size := "?"
+			if n, ok := item.DecodedLen(); ok {
+				size = fmt.Sprint(n)
+			}
+			hashOf := "content"
+			if !decompressed {
+				hashOf = "stored"
+			}
+			fmt.Fprintf(w, "%s\t%s bytes\tstored %d bytes\t%s\tsha256 (%s) %x\n", item.Filename, size, len(item.Payload), item.Compression, hashOf, sha256.Sum256(content))
+
+			if textconvFlags.Text && decompressed && isText(content) {
+				for _, line := range splitLines(decodeText(content)) {
+					fmt.Fprintf(w, "    %s\n", line)
+				}
+			}
+		}
+		return w.Flush()
+	},
+}
+
+var textconvFlags struct {
+	Text bool
+}
+
+func init() {
+	textconvCmd.Flags().BoolVar(&textconvFlags.Text, "text", false, "follow each text file (such as .cs and .mis scripts) with its content")
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change in a unified diff.
+const diffContextLines = 3
+
+// lineEdit is one line of a line-by-line diff: kept (' '), removed ('-') or added ('+').
+type lineEdit struct {
+	Op   byte
+	Line string
+}
+
+// splitLines splits text into lines, without line endings ("\n" or "\r\n").
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines
+}
+
+// diffLines returns a shortest edit script turning a into b (Myers' algorithm), in order.
+func diffLines(a, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	max := n + m
+	off := max + 1
+	v := make([]int, 2*max+3) // k -> furthest x on diagonal k, at v[off+k]
+
+	// trace[d] is v (for diagonals -d-1..d+1) before round d, for backtracking
+	var trace [][]int
+rounds:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1] // down: insertion
+			} else {
+				x = v[off+k-1] + 1 // right: deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				break rounds
+			}
+		}
+	}
+
+	var edits []lineEdit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		tv := trace[d]
+		at := func(k int) int { return tv[k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			edits = append(edits, lineEdit{' ', a[x-1]})
+			x, y = x-1, y-1
+		}
+		if x == prevX {
+			edits = append(edits, lineEdit{'+', b[y-1]})
+			y--
+		} else {
+			edits = append(edits, lineEdit{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		edits = append(edits, lineEdit{' ', a[x-1]})
+		x, y = x-1, y-1
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// unifiedDiff returns the unified diff (as by diff -u) from text a, labeled aName, to text b, labeled bName. If the
+// texts have the same lines, it returns "".
+func unifiedDiff(aName, bName, a, b string) string {
+	edits := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		// Find the next change, and extend the hunk until there are more than 2*diffContextLines unchanged lines
+		for start < len(edits) && edits[start].Op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		end := start
+		for i := start; i < len(edits) && i-end <= 2*diffContextLines; i++ {
+			if edits[i].Op != ' ' {
+				end = i + 1
+			}
+		}
+		hunkStart, hunkEnd := start-diffContextLines, end+diffContextLines
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		if hunkEnd > len(edits) {
+			hunkEnd = len(edits)
+		}
+
+		// Line numbers of the hunk in a and b
+		aLine, bLine := 1, 1
+		for _, e := range edits[:hunkStart] {
+			if e.Op != '+' {
+				aLine++
+			}
+			if e.Op != '-' {
+				bLine++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, e := range edits[hunkStart:hunkEnd] {
+			if e.Op != '+' {
+				aCount++
+			}
+			if e.Op != '-' {
+				bCount++
+			}
+		}
+		if aCount == 0 {
+			aLine-- // diff -u convention for empty ranges
+		}
+		if bCount == 0 {
+			bLine--
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, e := range edits[hunkStart:hunkEnd] {
+			out.WriteByte(e.Op)
+			out.WriteString(e.Line)
+			out.WriteByte('\n')
+		}
+		start = hunkEnd
+	}
+	return out.String()
+}
@@ -1,16 +1,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/iambob314/vol"
 	"github.com/spf13/cobra"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 var unpackCmd = &cobra.Command{
 	Use:  "unpack volfile [outdir] [filenames...]",
-	Long: "vol unpack unpacks the contents of a .vol file",
+	Long: "vol unpack unpacks the contents of a .vol file (or a Tribes 2 .vl2 file)",
 	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		fn, outdir := args[0], "."
@@ -29,61 +31,134 @@ var unpackCmd = &cobra.Command{
 			return fmt.Errorf("%s is not a directory: %w", outdir, err)
 		}
 
-		var v vol.File
-		if data, err := os.ReadFile(fn); err != nil {
-			return fmt.Errorf("could not read file %s: %w", fn, err)
-		} else if err := v.Parse(data); err != nil {
-			return fmt.Errorf("could not parse file %s: %w", fn, err)
+		a, err := openArchive(fn)
+		if err != nil {
+			return err
 		}
+		defer a.Close()
 
-		for _, item := range v.Items {
-			fnInVol := filepath.Clean(item.Filename)
+		numUnsafe, collisions := 0, caseCollisions{Dir: outdir}
+		for _, e := range a.List() {
+			fnInVol := e.Name.Clean()
 			if !fnmatch.Match(fnInVol) {
 				continue
 			}
 
-			if item.Compression != vol.None {
-				fmt.Printf("cannot unpack %s; compression %s unsupported\n", item.Filename, item.Compression)
+			content, err := a.Open(string(e.Name))
+			if errors.Is(err, vol.ErrUnsupportedCompression) {
+				fmt.Printf("cannot unpack %s; compression %s unsupported\n", e.Name, e.Compression)
 				continue
+			} else if err != nil {
+				return fmt.Errorf("could not decompress %s: %w", e.Name, err)
 			}
 
 			fn := fnInVol
 			if unpackFlags.StripPaths {
-				fn = filepath.Base(fn)
-				if fn == "." || fn == "/" {
-					fmt.Println("skipping empty filename")
+				fn = fn.Base()
+			}
+
+			fnFull, err := fn.JoinHost(outdir)
+			if err != nil {
+				if !unpackFlags.AllowUnsafePaths {
+					fmt.Printf("skipping file: %v (use --allow-unsafe-paths to unpack anyway)\n", err)
+					numUnsafe++
 					continue
 				}
+				fnFull = filepath.Join(outdir, fn.HostPath())
 			}
 
-			fnDir, fnBase := filepath.Dir(fn), filepath.Base(fn)
-			if fnDir != "." {
-				fnFullDir := filepath.Join(outdir, fnDir)
-				if err := os.MkdirAll(fnFullDir, 0666|os.ModeDir); err != nil {
+			collisions.Check(fn)
+
+			if fnFullDir := filepath.Dir(fnFull); fnFullDir != "." {
+				if err := os.MkdirAll(fnFullDir, 0777); err != nil {
 					return fmt.Errorf("could not create directory path %s: %w", fnFullDir, err)
 				}
 			}
 
-			fnFull := filepath.Join(outdir, fnDir, fnBase)
-			if err := os.WriteFile(fnFull, item.Payload, 0666); err != nil {
+			if err := os.WriteFile(fnFull, content, 0666); err != nil {
 				return fmt.Errorf("could not create file %s: %w", fnFull, err)
 			}
 
-			if fnFull == fnInVol {
-				fmt.Printf("unpacked %s\n", fnFull)
+			if fnFull == fnInVol.HostPath() {
+				fmt.Printf("unpacked %s\n", fnInVol)
 			} else {
 				fmt.Printf("unpacked %s to %s\n", fnInVol, fnFull)
 			}
 		}
 
+		if numUnsafe > 0 {
+			return fmt.Errorf("skipped %d file(s) with unsafe names", numUnsafe)
+		}
 		return nil
 	},
 }
 
+// caseCollisions detects unpacked paths (or their parent directories) that differ only in case. The engine treats such
+// paths as the same file, but a case-sensitive filesystem does not, and vice versa on case-insensitive filesystems.
+type caseCollisions struct {
+	Dir string // directory files are unpacked into
+
+	seen          map[string]vol.Path // case-folded path -> first path seen
+	warned        map[vol.Path]bool   // paths already warned about
+	caseSensitive *bool               // whether Dir is on a case-sensitive filesystem; probed on first collision
+}
+
+// Check records fn (and each of its parent directories), printing a warning for each that differs only in case from a
+// previously recorded path.
+func (c *caseCollisions) Check(fn vol.Path) {
+	if c.seen == nil {
+		c.seen, c.warned = make(map[string]vol.Path), make(map[vol.Path]bool)
+	}
+
+	var prefix vol.Path
+	for i, elem := range fn.Elems() {
+		if i > 0 {
+			prefix += `\`
+		}
+		prefix += vol.Path(elem)
+
+		key := prefix.Key(false)
+		other, ok := c.seen[key]
+		if !ok {
+			c.seen[key] = prefix
+			continue
+		} else if other == prefix || c.warned[prefix] {
+			continue
+		}
+		c.warned[prefix] = true
+
+		if c.caseSensitive == nil {
+			sensitive := isCaseSensitiveDir(c.Dir)
+			c.caseSensitive = &sensitive
+		}
+		if *c.caseSensitive {
+			fmt.Printf("warning: %s and %s differ only in case; they are unpacked separately, but the game treats them as the same file\n", other, prefix)
+		} else {
+			fmt.Printf("warning: %s and %s differ only in case; they are unpacked to the same place on this filesystem\n", other, prefix)
+		}
+	}
+}
+
+// isCaseSensitiveDir reports whether the filesystem containing dir distinguishes filenames differing only in case. If it
+// cannot be determined, it assumes so.
+func isCaseSensitiveDir(dir string) bool {
+	f, err := os.CreateTemp(dir, ".VolCaseProbe*")
+	if err != nil {
+		return true
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(f.Name()), strings.ToLower(filepath.Base(f.Name()))))
+	return err != nil
+}
+
 var unpackFlags struct {
-	StripPaths bool
+	StripPaths       bool
+	AllowUnsafePaths bool
 }
 
 func init() {
 	unpackCmd.Flags().BoolVar(&unpackFlags.StripPaths, "strip-paths", false, "ignore file paths in the vol; unpack with no subdirectories")
+	unpackCmd.Flags().BoolVar(&unpackFlags.AllowUnsafePaths, "allow-unsafe-paths", false, "unpack files even if their names are absolute, refer to parent directories (..),\nor refer to devices; by default such files are skipped, as they may be written\noutside outdir")
 }
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/iambob314/vol"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// volignoreFilename is the name of files listing patterns of files to skip when packing a directory recursively.
+const volignoreFilename = ".volignore"
+
+// matchAnyLevel reports whether name matches pattern, as gitignore does: a pattern containing a separator (other than
+// at the end) matches the whole name, relative to the start; a pattern without one matches the last element of name,
+// at any level. Matching ignores case unless --case-sensitive.
+func matchAnyLevel(name vol.Path, pattern string) bool {
+	pattern = strings.TrimRight(pattern, `\/`)
+	if !strings.ContainsAny(pattern, `\/`) {
+		name = name.Base()
+	}
+	pattern = strings.TrimLeft(pattern, `\/`)
+	return FilenameSet{pattern}.Match(name)
+}
+
+// packFilter selects files to pack by their names in the vol (see --include and --exclude).
+type packFilter struct {
+	Include, Exclude []string
+}
+
+// Match reports whether a file named name should be packed: it must match an Include pattern (if there are any), and
+// must not match any Exclude pattern. Patterns are matched as by matchAnyLevel.
+func (f packFilter) Match(name vol.Path) bool {
+	included := len(f.Include) == 0
+	for _, pattern := range f.Include {
+		included = included || matchAnyLevel(name, pattern)
+	}
+	for _, pattern := range f.Exclude {
+		included = included && !matchAnyLevel(name, pattern)
+	}
+	return included
+}
+
+// ignoreRule is a pattern from a .volignore file. The syntax is a subset of .gitignore: blank lines and lines starting
+// with # are skipped, a leading ! re-includes files excluded by an earlier pattern, and a trailing / or \ matches only
+// directories. The last matching rule wins.
+type ignoreRule struct {
+	Dir     vol.Path // directory containing the .volignore, relative to the walk root ("" for the root itself)
+	Pattern string
+	Negate  bool
+	DirOnly bool
+}
+
+// Match reports whether the rule applies to rel (a path relative to the walk root).
+func (r ignoreRule) Match(rel vol.Path, isDir bool) bool {
+	if r.DirOnly && !isDir {
+		return false
+	}
+	if r.Dir != "" {
+		prefix := r.Dir.Key(rootFlags.CaseSensitive) + `\`
+		if !strings.HasPrefix(rel.Key(rootFlags.CaseSensitive), prefix) {
+			return false
+		}
+		rel = rel[len(prefix):]
+	}
+	return matchAnyLevel(rel, r.Pattern)
+}
+
+// readVolignore reads the ignore rules from the .volignore file in host directory dir (relative path rel), if any.
+func readVolignore(dir string, rel vol.Path) ([]ignoreRule, error) {
+	f, err := os.Open(filepath.Join(dir, volignoreFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{Dir: rel}
+		if strings.HasPrefix(line, "!") {
+			rule.Negate, line = true, line[1:]
+		}
+		if strings.HasSuffix(line, "/") || strings.HasSuffix(line, `\`) {
+			rule.DirOnly = true
+		}
+		rule.Pattern = line
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// walkPackDir returns the host paths of all files under host directory root, in lexical order, except .volignore files
+// and files (or directories) excluded by them.
+func walkPackDir(root string) ([]string, error) {
+	var (
+		files []string
+		rules []ignoreRule
+	)
+	err := filepath.WalkDir(root, func(fn string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		var rel vol.Path
+		if fn != root {
+			relFN, err := filepath.Rel(root, fn)
+			if err != nil {
+				return err
+			}
+			rel = vol.PathFromHost(relFN)
+
+			ignored := false
+			for _, rule := range rules {
+				if rule.Match(rel, d.IsDir()) {
+					ignored = !rule.Negate
+				}
+			}
+			if ignored && d.IsDir() {
+				return filepath.SkipDir
+			} else if ignored {
+				return nil
+			}
+		}
+
+		switch {
+		case d.IsDir():
+			dirRules, err := readVolignore(fn, rel)
+			if err != nil {
+				return fmt.Errorf("could not read %s in %s: %w", volignoreFilename, fn, err)
+			}
+			rules = append(rules, dirRules...)
+		case d.Name() == volignoreFilename:
+			// never packed
+		default:
+			if stat, err := os.Stat(fn); err != nil { // follow symlinks
+				return err
+			} else if stat.Mode().IsRegular() {
+				files = append(files, fn)
+			}
+		}
+		return nil
+	})
+	return files, err
+}
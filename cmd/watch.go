@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+// fileStamp is what watch checks to detect that a file changed.
+type fileStamp struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// snapshotDir returns the stamps of the files vol sync would consider in host directory dir, by host path.
+func snapshotDir(dir, volFN string) (map[string]fileStamp, error) {
+	fns, err := walkPackDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not walk directory %s: %w", dir, err)
+	}
+
+	stamps := make(map[string]fileStamp, len(fns))
+	for _, fn := range fns {
+		if isVolOutput(fn, volFN) {
+			continue
+		}
+		stat, err := os.Stat(fn)
+		if os.IsNotExist(err) {
+			continue // deleted since the walk
+		} else if err != nil {
+			return nil, err
+		}
+		stamps[fn] = fileStamp{Size: stat.Size(), ModTime: stat.ModTime()}
+	}
+	return stamps, nil
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for fn, stamp := range a {
+		if other, ok := b[fn]; !ok || other.Size != stamp.Size || !other.ModTime.Equal(stamp.ModTime) {
+			return false
+		}
+	}
+	return true
+}
+
+var watchCmd = &cobra.Command{
+	Use:  "watch dir volfile",
+	Long: "vol watch keeps a .vol file in sync with a directory tree (see vol sync) until interrupted: the directory is polled\nfor changes, and once they settle (see --debounce), the vol is updated",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, volFN := args[0], args[1]
+
+		if err := syncDir(dir, volFN, false, watchFlags.StrictNames, false); err != nil {
+			return err
+		}
+		last, err := snapshotDir(dir, volFN)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("watching %s for changes (Ctrl-C to stop)\n", dir)
+
+		for {
+			time.Sleep(watchFlags.Interval)
+			cur, err := snapshotDir(dir, volFN)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			} else if sameStamps(cur, last) {
+				continue
+			}
+
+			// Wait for changes to stop (e.g. an editor saving several files, or writing a file in several steps)
+			for {
+				time.Sleep(watchFlags.Debounce)
+				next, err := snapshotDir(dir, volFN)
+				if err == nil && sameStamps(next, cur) {
+					break
+				} else if err == nil {
+					cur = next
+				}
+			}
+
+			// Errors are not fatal; a later change may fix them (e.g. a file unreadable while being written)
+			if err := syncDir(dir, volFN, false, watchFlags.StrictNames, false); err != nil {
+				fmt.Println(err)
+			}
+			last = cur
+		}
+	},
+}
+
+var watchFlags struct {
+	Interval    time.Duration
+	Debounce    time.Duration
+	StrictNames string
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchFlags.Interval, "interval", 250*time.Millisecond, "how often to check the directory for changes")
+	watchCmd.Flags().DurationVar(&watchFlags.Debounce, "debounce", 250*time.Millisecond, "update the vol once files have not changed for this long")
+	addStrictNamesFlag(watchCmd, &watchFlags.StrictNames)
+}
@@ -0,0 +1,69 @@
+package vol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnsupportedCompression = errors.New("unsupported compression type")
+
+// Decompress returns the decompressed content of the item, within the Limits of the File it was parsed by (or
+// DefaultLimits). The item itself is not modified. If the item's compression type is not supported, an error wrapping
+// ErrUnsupportedCompression is returned.
+func (v *Item) Decompress() (ByteBuffer, error) {
+	if v.limits != nil {
+		return v.DecompressLimited(v.limits)
+	}
+	return v.DecompressLimited(&DefaultLimits)
+}
+
+// DecompressLimited is like Decompress, but returns an error wrapping ErrLimitExceeded if the item's decoded size
+// exceeds limits.MaxItemSize.
+func (v *Item) DecompressLimited(limits *Limits) (ByteBuffer, error) {
+	if size, ok := v.DecodedLen(); ok {
+		if err := limits.checkItemSize(v.Filename, size); err != nil {
+			return nil, err
+		}
+	}
+
+	switch v.Compression {
+	case None:
+		return v.Payload, nil
+	default:
+		return nil, fmt.Errorf("cannot decompress %s: %w", v.Compression, ErrUnsupportedCompression)
+	}
+}
+
+// Recompress converts the item's payload to compression type c. If either the item's current compression type or c is
+// not supported, an error wrapping ErrUnsupportedCompression is returned and the item is not modified.
+func (v *Item) Recompress(c CompressionType) error {
+	if c == v.Compression {
+		return nil
+	}
+
+	content, err := v.Decompress()
+	if err != nil {
+		return err
+	}
+
+	switch c {
+	case None:
+		v.Payload = content
+	default:
+		return fmt.Errorf("cannot compress %s: %w", c, ErrUnsupportedCompression)
+	}
+	v.Compression = c
+	return nil
+}
+
+// ParseCompressionType returns the CompressionType with the given name (as returned by CompressionType.String),
+// ignoring case.
+func ParseCompressionType(name string) (CompressionType, error) {
+	for _, c := range []CompressionType{None, RLE, LZ, LZH} {
+		if strings.EqualFold(name, c.String()) {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown compression type %q (expected %s, %s, %s, or %s)", name, None, RLE, LZ, LZH)
+}
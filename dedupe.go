@@ -0,0 +1,64 @@
+package vol
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// SharedPayloads returns the groups of items (as indices into v.Items, in order) that share a single payload in the vol
+// file v was parsed from, as written with Dedupe. Items with empty payloads, and items added since parsing, are never
+// reported.
+func (v *File) SharedPayloads() [][]int {
+	var (
+		groups  [][]int
+		byStart = make(map[int]int) // payloadOffset -> index in groups
+	)
+	for i, item := range v.Items {
+		if item.payloadOffset == 0 || len(item.Payload) == 0 {
+			continue
+		}
+		if g, ok := byStart[item.payloadOffset]; ok {
+			groups[g] = append(groups[g], i)
+		} else {
+			byStart[item.payloadOffset] = len(groups)
+			groups = append(groups, []int{i})
+		}
+	}
+
+	shared := groups[:0]
+	for _, g := range groups {
+		if len(g) > 1 {
+			shared = append(shared, g)
+		}
+	}
+	return shared
+}
+
+// payloadOwners returns, for each item, the index of the first item with an identical payload (which is the item
+// itself, unless v.Dedupe is set and an earlier item has the same payload). Only the owners' payloads are stored.
+func (v *File) payloadOwners() []int {
+	owners := make([]int, len(v.Items))
+	var seen map[[sha256.Size]byte][]int // payload hash -> owners with that hash
+	if v.Dedupe {
+		seen = make(map[[sha256.Size]byte][]int)
+	}
+
+	for i, item := range v.Items {
+		owners[i] = i
+		if seen == nil {
+			continue
+		}
+
+		sum := sha256.Sum256(item.Payload)
+		for _, owner := range seen[sum] {
+			if bytes.Equal(v.Items[owner].Payload, item.Payload) { // never trust the hash alone
+				owners[i] = owner
+				break
+			}
+		}
+		if owners[i] == i {
+			seen[sum] = append(seen[sum], i)
+		}
+	}
+	return owners
+}
@@ -0,0 +1,118 @@
+package vol
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DirArchive is an Archive of the files under the directory Root (as in an unpacked vol). Names are paths relative to
+// Root. Write writes files immediately; names that are not safe paths (see CheckSafe) cannot be written.
+//
+// Unless CaseSensitive, a name not found as given is looked up ignoring case, in an index of the files under Root built
+// on first use (and updated by Write), so files created other than by Write after that are only found by exact name.
+type DirArchive struct {
+	Root          string
+	CaseSensitive bool
+
+	folded map[string]Path // name key (see Path.Key) -> name, for case-insensitive lookups; built lazily
+}
+
+func (a *DirArchive) List() []Entry {
+	var entries []Entry
+	_ = filepath.WalkDir(a.Root, func(fn string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil // unreadable directories are skipped
+		}
+		rel, err := filepath.Rel(a.Root, fn)
+		if err != nil {
+			return nil
+		}
+		if stat, err := os.Stat(fn); err == nil && stat.Mode().IsRegular() { // follow symlinks
+			entries = append(entries, Entry{Name: PathFromHost(rel), Size: stat.Size(), StoredSize: stat.Size(), Compression: "None"})
+		}
+		return nil
+	})
+	return entries
+}
+
+// hostPath returns the host path of the existing file named name, if any.
+func (a *DirArchive) hostPath(name string) (string, bool) {
+	fn, err := Path(name).JoinHost(a.Root)
+	if err != nil {
+		return "", false
+	} else if stat, err := os.Stat(fn); err == nil && stat.Mode().IsRegular() {
+		return fn, true
+	} else if a.CaseSensitive {
+		return "", false
+	}
+
+	// The filesystem may be case sensitive; look for a file whose name differs only in case
+	if a.folded == nil {
+		a.folded = make(map[string]Path)
+		for _, e := range a.List() {
+			if key := e.Name.Key(false); a.folded[key] == "" {
+				a.folded[key] = e.Name
+			}
+		}
+	}
+	other, ok := a.folded[Path(name).Key(false)]
+	if !ok {
+		return "", false
+	}
+	fn = filepath.Join(a.Root, other.HostPath())
+	if stat, err := os.Stat(fn); err != nil || !stat.Mode().IsRegular() {
+		return "", false
+	}
+	return fn, true
+}
+
+func (a *DirArchive) Stat(name string) (Entry, error) {
+	fn, ok := a.hostPath(name)
+	if !ok {
+		return Entry{}, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	stat, err := os.Stat(fn)
+	if err != nil {
+		return Entry{}, err
+	}
+	rel, err := filepath.Rel(a.Root, fn)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Name: PathFromHost(rel), Size: stat.Size(), StoredSize: stat.Size(), Compression: "None"}, nil
+}
+
+func (a *DirArchive) Open(name string) ([]byte, error) {
+	fn, ok := a.hostPath(name)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	return os.ReadFile(fn)
+}
+
+func (a *DirArchive) Write(name string, data []byte) error {
+	fn, ok := a.hostPath(name)
+	if !ok {
+		var err error
+		if fn, err = Path(name).JoinHost(a.Root); err != nil {
+			return err
+		} else if err := os.MkdirAll(filepath.Dir(fn), 0777); err != nil {
+			return err
+		}
+		if a.folded != nil {
+			a.folded[Path(name).Key(false)] = Path(name)
+		}
+	}
+	return os.WriteFile(fn, data, 0666)
+}
+
+// Commit does nothing, as Write writes files immediately.
+func (a *DirArchive) Commit() error {
+	return nil
+}
+
+func (a *DirArchive) Close() error {
+	return nil
+}
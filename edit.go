@@ -0,0 +1,97 @@
+package vol
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrExists   = errors.New("item already exists")
+	ErrNotFound = errors.New("item not found")
+)
+
+// Lookup returns the item named name, if any.
+func (v *File) Lookup(name string) (*Item, bool) {
+	idx, ok := v.indexOf(name)
+	if !ok {
+		return nil, false
+	}
+	return &v.Items[idx], true
+}
+
+// Has reports whether the vol contains an item named name.
+func (v *File) Has(name string) bool {
+	_, ok := v.indexOf(name)
+	return ok
+}
+
+// Add appends item to the end of the vol. If an item with the same name already exists, ErrExists is returned.
+func (v *File) Add(item Item) error {
+	if v.Has(item.Filename) {
+		return fmt.Errorf("%s: %w", item.Filename, ErrExists)
+	}
+	v.index[v.nameKey(item.Filename)] = len(v.Items)
+	v.Items = append(v.Items, item)
+	return nil
+}
+
+// Replace replaces the existing item with the same name as item, keeping its position in the vol. If no such item
+// exists, ErrNotFound is returned.
+func (v *File) Replace(item Item) error {
+	idx, ok := v.indexOf(item.Filename)
+	if !ok {
+		return fmt.Errorf("%s: %w", item.Filename, ErrNotFound)
+	}
+	v.Items[idx] = item
+	return nil
+}
+
+// Remove removes the item named name. If no such item exists, ErrNotFound is returned.
+func (v *File) Remove(name string) error {
+	idx, ok := v.indexOf(name)
+	if !ok {
+		return fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	v.Items = append(v.Items[:idx], v.Items[idx+1:]...)
+	v.Reindex()
+	return nil
+}
+
+// Rename renames the item named oldName to newName, keeping its position and content. If no item named oldName exists,
+// ErrNotFound is returned; if a different item named newName exists, ErrExists is returned.
+func (v *File) Rename(oldName, newName string) error {
+	idx, ok := v.indexOf(oldName)
+	if !ok {
+		return fmt.Errorf("%s: %w", oldName, ErrNotFound)
+	}
+	if otherIdx, ok := v.indexOf(newName); ok && otherIdx != idx {
+		return fmt.Errorf("%s: %w", newName, ErrExists)
+	}
+
+	delete(v.index, v.nameKey(oldName))
+	v.index[v.nameKey(newName)] = idx
+	v.Items[idx].Filename = newName
+	return nil
+}
+
+// Reindex rebuilds the name index used by Lookup, Has, Add, Replace, Remove, and Rename. It need only be called after
+// modifying Items directly (rather than through those methods) once any of them have been used.
+func (v *File) Reindex() {
+	v.index = make(map[string]int, len(v.Items))
+	for i, it := range v.Items {
+		v.index[v.nameKey(it.Filename)] = i
+	}
+}
+
+func (v *File) indexOf(name string) (int, bool) {
+	if v.index == nil {
+		v.Reindex()
+	}
+	idx, ok := v.index[v.nameKey(name)]
+	return idx, ok
+}
+
+// nameKey returns the key for name in the name index (see Path.Key).
+func (v *File) nameKey(name string) string {
+	return Path(name).Key(v.CaseSensitive)
+}
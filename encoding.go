@@ -0,0 +1,117 @@
+package vol
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of filenames stored in a vol file. Filenames in a File are always UTF-8; they are
+// decoded from Encoding by Parse, and encoded to it by Store.
+type Encoding byte
+
+const (
+	EncodingDefault     = Encoding(iota) // use DefaultEncoding
+	EncodingWindows1252                  // Windows-1252 (a superset of Latin-1), as used by the game on Western systems
+	EncodingRaw                          // no conversion: filename bytes are used as-is, even if not valid UTF-8
+)
+
+// DefaultEncoding is the Encoding used by a File whose Encoding is EncodingDefault.
+var DefaultEncoding = EncodingWindows1252
+
+// ParseEncoding returns the Encoding with the given name (as returned by Encoding.String).
+func ParseEncoding(name string) (Encoding, error) {
+	for _, e := range []Encoding{EncodingWindows1252, EncodingRaw} {
+		if strings.EqualFold(name, e.String()) {
+			return e, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown filename encoding %q (expected %s or %s)", name, EncodingWindows1252, EncodingRaw)
+}
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingDefault:
+		return "default"
+	case EncodingWindows1252:
+		return "windows-1252"
+	case EncodingRaw:
+		return "raw"
+	default:
+		return fmt.Sprintf("Encoding(%d)", byte(e))
+	}
+}
+
+func (e Encoding) resolve() Encoding {
+	if e == EncodingDefault {
+		return DefaultEncoding
+	}
+	return e
+}
+
+// Decode converts a filename from e to UTF-8.
+func (e Encoding) Decode(fn []byte) string {
+	if e.resolve() == EncodingRaw {
+		return string(fn)
+	}
+
+	ascii := true
+	for _, c := range fn {
+		ascii = ascii && c < utf8.RuneSelf
+	}
+	if ascii {
+		return string(fn)
+	}
+
+	var sb strings.Builder
+	sb.Grow(2 * len(fn))
+	for _, c := range fn {
+		if 0x80 <= c && c < 0xA0 {
+			sb.WriteRune(windows1252High[c-0x80])
+		} else {
+			sb.WriteRune(rune(c)) // same as Latin-1 / Unicode
+		}
+	}
+	return sb.String()
+}
+
+// Encode converts a UTF-8 filename to e, appending the result to buf. If fn contains a character that e cannot
+// represent, an error is returned.
+func (e Encoding) Encode(fn string, buf *ByteBuffer) error {
+	if e.resolve() == EncodingRaw {
+		buf.AppendString(fn)
+		return nil
+	}
+
+	for i, r := range fn {
+		switch {
+		case r == utf8.RuneError && !strings.HasPrefix(fn[i:], string(utf8.RuneError)):
+			return fmt.Errorf("filename %q is not valid UTF-8", fn)
+		case r < 0x80 || 0xA0 <= r && r <= 0xFF:
+			buf.Append(byte(r))
+		default:
+			b, ok := windows1252Reverse[r]
+			if !ok {
+				return fmt.Errorf("filename %q: character %q cannot be represented in %s", fn, r, EncodingWindows1252)
+			}
+			buf.Append(b)
+		}
+	}
+	return nil
+}
+
+// windows1252High maps bytes 0x80-0x9F of Windows-1252 to Unicode. The five bytes undefined in Windows-1252 map to the
+// C1 control characters with the same value (as Windows itself does), so that any byte string decodes and re-encodes
+// unchanged.
+var windows1252High = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+var windows1252Reverse = func() map[rune]byte {
+	m := make(map[rune]byte, len(windows1252High))
+	for i, r := range windows1252High {
+		m[r] = byte(0x80 + i)
+	}
+	return m
+}()
@@ -0,0 +1,84 @@
+package vol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrLimitExceeded is wrapped by errors for vol files that exceed the configured Limits.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Limits bounds the resources that parsing and decompressing a vol file may use, to protect against malicious or
+// corrupt input (e.g. a footer declaring millions of items, or an LZH item declaring a huge decoded length). A zero or
+// negative field means no limit.
+type Limits struct {
+	MaxItems       int   // maximum number of items in a vol
+	MaxItemSize    int64 // maximum decoded (decompressed) size of an item, in bytes
+	MaxTotalSize   int64 // maximum total decoded size of all items in a vol, in bytes
+	MaxFilenameLen int   // maximum length of an item filename, in bytes
+}
+
+// DefaultLimits are the Limits used by File.Parse, Item.Decompress and VL2Archive when no other Limits are given. They
+// are far beyond anything the engine itself can load.
+var DefaultLimits = Limits{
+	MaxItems:       1 << 16,
+	MaxItemSize:    256 << 20,
+	MaxTotalSize:   2 << 30,
+	MaxFilenameLen: 1024,
+}
+
+// limits returns v.Limits, or DefaultLimits if nil.
+func (v *File) limits() *Limits {
+	if v.Limits != nil {
+		return v.Limits
+	}
+	return &DefaultLimits
+}
+
+func (l *Limits) checkItems(n int) error {
+	if l.MaxItems > 0 && n > l.MaxItems {
+		return fmt.Errorf("vol has %d items, more than the maximum of %d: %w", n, l.MaxItems, ErrLimitExceeded)
+	}
+	return nil
+}
+
+func (l *Limits) checkFilename(fn []byte) error {
+	if l.MaxFilenameLen > 0 && len(fn) > l.MaxFilenameLen {
+		return fmt.Errorf("filename of %d bytes is longer than the maximum of %d: %w", len(fn), l.MaxFilenameLen, ErrLimitExceeded)
+	}
+	return nil
+}
+
+func (l *Limits) checkItemSize(filename string, size int64) error {
+	if l.MaxItemSize > 0 && size > l.MaxItemSize {
+		return fmt.Errorf("item %s has decoded size %d, more than the maximum of %d: %w", filename, size, l.MaxItemSize, ErrLimitExceeded)
+	}
+	return nil
+}
+
+func (l *Limits) checkTotalSize(size int64) error {
+	if l.MaxTotalSize > 0 && size > l.MaxTotalSize {
+		return fmt.Errorf("vol has total decoded size %d, more than the maximum of %d: %w", size, l.MaxTotalSize, ErrLimitExceeded)
+	}
+	return nil
+}
+
+// DecodedLen returns the size of the item's content after decompression, if it can be determined without
+// decompressing: for uncompressed items this is the payload length, and LZH items declare it in their first 4 bytes.
+// Note that the declared length of a compressed item comes from the (possibly untrusted) vol file itself.
+func (v *Item) DecodedLen() (n int64, ok bool) {
+	switch v.Compression {
+	case None:
+		return int64(len(v.Payload)), true
+	case LZH:
+		if len(v.Payload) == 0 {
+			return 0, true // empty content is stored without a length prefix
+		} else if len(v.Payload) < 4 {
+			return 0, false
+		}
+		return int64(binary.LittleEndian.Uint32(v.Payload)), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,31 @@
+package vol
+
+// MappedFile is a File parsed directly from a read-only memory mapping of a vol file (see OpenMmap). Item payloads
+// refer directly into the mapping: they must not be modified, and must not be used after Close.
+type MappedFile struct {
+	File
+	data []byte
+}
+
+// OpenMmap maps the vol file at path into memory read-only and parses it. On platforms where memory mapping is not
+// supported, the file is read into memory instead. Close must be called to release the mapping.
+func OpenMmap(path string) (*MappedFile, error) {
+	data, err := mmapFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MappedFile{data: data}
+	if err := m.Parse(data); err != nil {
+		_ = m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Close releases the mapping. After Close, the items of m (and their payloads) must no longer be used.
+func (m *MappedFile) Close() error {
+	data := m.data
+	m.data, m.Items, m.index = nil, nil, nil
+	return munmapFile(data)
+}
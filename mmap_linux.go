@@ -0,0 +1,41 @@
+//go:build linux
+
+package vol
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func mmapFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := stat.Size()
+	if size == 0 {
+		return nil, nil // cannot map an empty file; parsing the empty slice reports the error
+	} else if int64(int(size)) != size {
+		return nil, fmt.Errorf("%s is too large to map (%d bytes)", path, size)
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("could not map %s: %w", path, err)
+	}
+	return data, nil
+}
+
+func munmapFile(data []byte) error {
+	if data == nil {
+		return nil
+	}
+	return syscall.Munmap(data)
+}
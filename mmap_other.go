@@ -0,0 +1,9 @@
+//go:build !linux
+
+package vol
+
+import "os"
+
+func mmapFile(path string) ([]byte, error) { return os.ReadFile(path) }
+
+func munmapFile(data []byte) error { return nil }
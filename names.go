@@ -0,0 +1,82 @@
+package vol
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidName is wrapped by errors for filenames that a game will not load (see NameRules).
+var ErrInvalidName = errors.New("invalid name")
+
+// NameRules are constraints on item filenames, beyond what the vol format itself requires, that a game's resource
+// manager needs in order to load the item. Vols containing names that break these rules can be packed and read, but the
+// game silently fails to find those items.
+type NameRules struct {
+	Name       string // name of the game the rules are for
+	MaxPathLen int    // maximum length of a filename (including directories), in bytes
+	DOS83      bool   // each path element must be a DOS 8.3 name
+}
+
+var (
+	// TribesNames are the name rules for Starsiege: Tribes.
+	TribesNames = NameRules{Name: "tribes", MaxPathLen: 255}
+
+	// StarsiegeNames are the name rules for Starsiege, which additionally requires DOS 8.3 names.
+	StarsiegeNames = NameRules{Name: "starsiege", MaxPathLen: 255, DOS83: true}
+)
+
+// ParseNameRules returns the NameRules with the given Name.
+func ParseNameRules(name string) (NameRules, error) {
+	for _, r := range []NameRules{TribesNames, StarsiegeNames} {
+		if strings.EqualFold(name, r.Name) {
+			return r, nil
+		}
+	}
+	return NameRules{}, fmt.Errorf("unknown name rules %q (expected %s or %s)", name, TribesNames.Name, StarsiegeNames.Name)
+}
+
+// Check returns an error wrapping ErrInvalidName if name breaks the rules. Names must also be safe to extract (see
+// Path.CheckSafe), use '\' (not '/') as the separator, and have no empty or "." elements, elements ending in a space or
+// '.', or any of the characters < > : " | ? *.
+func (r NameRules) Check(name string) error {
+	if err := Path(name).CheckSafe(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidName, err)
+	}
+
+	if len(name) > r.MaxPathLen {
+		return fmt.Errorf("%s is %d bytes long, more than the maximum of %d: %w", name, len(name), r.MaxPathLen, ErrInvalidName)
+	} else if i := strings.IndexAny(name, `/<>:"|?*`); i != -1 {
+		return fmt.Errorf("%s contains forbidden character %q: %w", name, name[i], ErrInvalidName)
+	}
+
+	for _, elem := range strings.Split(name, `\`) {
+		switch {
+		case elem == "" || elem == ".":
+			return fmt.Errorf("%s contains an empty path element: %w", name, ErrInvalidName)
+		case strings.HasSuffix(elem, " ") || strings.HasSuffix(elem, "."):
+			return fmt.Errorf("%s contains path element %q ending in a space or '.': %w", name, elem, ErrInvalidName)
+		case r.DOS83 && !isDOS83(elem):
+			return fmt.Errorf("%s contains path element %q, which is not a DOS 8.3 name: %w", name, elem, ErrInvalidName)
+		}
+	}
+	return nil
+}
+
+// isDOS83 reports whether elem is a valid DOS 8.3 filename: 1-8 characters, optionally followed by '.' and 1-3
+// characters, using only letters, digits, and the characters ! # $ % & ' ( ) - @ ^ _ ` { } ~.
+func isDOS83(elem string) bool {
+	base, ext, hasExt := strings.Cut(elem, ".")
+	if len(base) < 1 || len(base) > 8 || hasExt && (len(ext) < 1 || len(ext) > 3) {
+		return false
+	}
+	for _, c := range []byte(base + ext) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'()-@^_`{}~", c) != -1:
+		default:
+			return false
+		}
+	}
+	return true
+}
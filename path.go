@@ -0,0 +1,180 @@
+package vol
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Path is a filename within a vol file. Vol files use '\' as the path separator (e.g. "dir\file3.txt") regardless of
+// the host OS; Path provides the equivalents of the path/filepath functions for such names, and conversion to and from
+// host paths. For leniency with vols written by other tools, '/' is also accepted as a separator.
+type Path string
+
+// PathSeparator is the canonical path separator in vol filenames.
+const PathSeparator = '\\'
+
+// PathFromHost converts a (relative) host filesystem path to a Path: the path is cleaned, and host separators are
+// replaced with PathSeparator.
+func PathFromHost(hostPath string) Path {
+	return Path(filepath.ToSlash(filepath.Clean(hostPath))).fromSlash()
+}
+
+// HostPath converts p to a host filesystem path, using the host's path separator.
+func (p Path) HostPath() string {
+	return filepath.FromSlash(p.toSlash())
+}
+
+// Clean returns the shortest equivalent of p, as path.Clean does, with all separators replaced with PathSeparator.
+func (p Path) Clean() Path {
+	return Path(path.Clean(p.toSlash())).fromSlash()
+}
+
+// Base returns the last element of p, as path.Base does.
+func (p Path) Base() Path {
+	return Path(path.Base(p.toSlash()))
+}
+
+// Dir returns all but the last element of p, as path.Dir does.
+func (p Path) Dir() Path {
+	return Path(path.Dir(p.toSlash())).fromSlash()
+}
+
+// Elems returns the elements of p (after cleaning), e.g. ["dir", "file3.txt"] for "dir\file3.txt".
+func (p Path) Elems() []string {
+	return strings.Split(p.Clean().toSlash(), "/")
+}
+
+// Key returns the canonical form of p for comparing names: all separators are PathSeparator and, unless caseSensitive,
+// ASCII letters are folded to lower case. Two names with the same (case-insensitive) key refer to the same file as far
+// as the engine is concerned.
+func (p Path) Key(caseSensitive bool) string {
+	key := p.fromSlash()
+	if !caseSensitive {
+		key = key.fold()
+	}
+	return string(key)
+}
+
+// Match reports whether p matches the shell pattern, ignoring case as the engine does. It is otherwise like
+// path.Match, but with either '\' or '/' as the path separator in both p and pattern, and a "**" path element in
+// pattern matches zero or more path elements (e.g. "scripts\**\*.cs" matches "scripts\a.cs" and "scripts\x\y\b.cs").
+// Since '\' is a separator, it cannot be used to escape special characters; use a character class (e.g. [*]) instead.
+// A malformed pattern matches nothing.
+func (p Path) Match(pattern string) bool {
+	return p.fold().MatchCase(string(Path(pattern).fold()))
+}
+
+// MatchCase is like Match, but case-sensitive.
+func (p Path) MatchCase(pattern string) bool {
+	return matchElems(strings.Split(Path(pattern).toSlash(), "/"), strings.Split(p.toSlash(), "/"))
+}
+
+// matchElems reports whether path elements elems match pattern elements patElems (see Match).
+func matchElems(patElems, elems []string) bool {
+	for len(patElems) > 0 {
+		if patElems[0] == "**" {
+			// Try matching the rest of the pattern against every suffix of elems (including the empty one)
+			for i := 0; i <= len(elems); i++ {
+				if matchElems(patElems[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(elems) == 0 {
+			return false
+		} else if matched, _ := path.Match(patElems[0], elems[0]); !matched {
+			return false
+		}
+		patElems, elems = patElems[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
+
+func (p Path) String() string { return string(p) }
+
+// toSlash returns p with '/' as the separator, for use with package path.
+func (p Path) toSlash() string {
+	return strings.ReplaceAll(string(p), string(PathSeparator), "/")
+}
+
+// fold returns p with ASCII letters folded to lower case, matching the engine's case-insensitive name comparison.
+func (p Path) fold() Path {
+	for i := 0; i < len(p); i++ {
+		if c := p[i]; 'A' <= c && c <= 'Z' {
+			b := []byte(p)
+			for j := i; j < len(b); j++ {
+				if c := b[j]; 'A' <= c && c <= 'Z' {
+					b[j] = c + ('a' - 'A')
+				}
+			}
+			return Path(b)
+		}
+	}
+	return p
+}
+
+// fromSlash returns p with each '/' replaced with PathSeparator.
+func (p Path) fromSlash() Path {
+	return Path(strings.ReplaceAll(string(p), "/", string(PathSeparator)))
+}
+
+// ErrUnsafePath is wrapped by errors for paths that could refer to a location outside the directory they are
+// extracted to, or to a device rather than a file.
+var ErrUnsafePath = errors.New("unsafe path")
+
+// CheckSafe returns an error wrapping ErrUnsafePath if p is not safe to extract relative to a directory on any OS: if p
+// is empty, absolute (e.g. "\dir", "C:dir", "\\server\share"), contains a ".." element, contains a ':' (a drive letter
+// or NTFS alternate data stream) or control character, or has an element that is a reserved DOS device name (CON,
+// NUL, COM1, etc., with or without an extension).
+func (p Path) CheckSafe() error {
+	s := p.toSlash()
+	switch {
+	case s == "" || path.Clean(s) == ".":
+		return fmt.Errorf("empty filename: %w", ErrUnsafePath)
+	case strings.HasPrefix(s, "/"):
+		return fmt.Errorf("%s is absolute: %w", p, ErrUnsafePath)
+	}
+
+	for _, elem := range strings.Split(s, "/") {
+		if elem == ".." {
+			return fmt.Errorf("%s refers to a parent directory: %w", p, ErrUnsafePath)
+		} else if strings.ContainsRune(elem, ':') {
+			return fmt.Errorf("%s contains a drive letter or stream name: %w", p, ErrUnsafePath)
+		} else if strings.IndexFunc(elem, func(r rune) bool { return r < ' ' || r == 0x7f }) != -1 {
+			return fmt.Errorf("%s contains a control character: %w", p, ErrUnsafePath)
+		} else if isDeviceName(elem) {
+			return fmt.Errorf("%s refers to device %s: %w", p, elem, ErrUnsafePath)
+		}
+	}
+	return nil
+}
+
+// JoinHost returns the host path of p within host directory dir, if p is safe (see CheckSafe).
+func (p Path) JoinHost(dir string) (string, error) {
+	if err := p.CheckSafe(); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, p.HostPath()), nil
+}
+
+// isDeviceName reports whether elem is a reserved DOS device name, which Windows treats as the device regardless of
+// directory or extension (e.g. "dir\nul.txt" is the NUL device).
+func isDeviceName(elem string) bool {
+	if i := strings.IndexByte(elem, '.'); i != -1 {
+		elem = elem[:i]
+	}
+	elem = strings.ToUpper(strings.TrimRight(elem, " "))
+	switch elem {
+	case "CON", "PRN", "AUX", "NUL", "CONIN$", "CONOUT$":
+		return true
+	}
+	if len(elem) == 4 && (strings.HasPrefix(elem, "COM") || strings.HasPrefix(elem, "LPT")) {
+		return '1' <= elem[3] && elem[3] <= '9'
+	}
+	return false
+}
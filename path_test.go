@@ -0,0 +1,52 @@
+package vol
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathCheckSafe(t *testing.T) {
+	tests := []struct {
+		name string
+		safe bool
+	}{
+		{``, false},
+		{`.`, false},
+		{`..\..\x`, false},
+		{`dir\..\..\x`, false},
+		{`../x`, false},
+		{`C:x`, false},
+		{`C:\x`, false},
+		{`\\srv\x`, false},
+		{`\x`, false},
+		{`/abs`, false},
+		{`dir\nul.txt`, false},
+		{`CON`, false},
+		{`com1.cs`, false},
+		{`file.txt:stream`, false},
+		{"dir\\a\x01.cs", false},
+
+		{`file1.txt`, true},
+		{`dir\file3.txt`, true},
+		{`dir/file3.txt`, true},
+		{`scripts\..x\a.cs`, true},
+		{`null.txt`, true},
+		{`console.cs`, true},
+	}
+	for _, tt := range tests {
+		err := Path(tt.name).CheckSafe()
+		if tt.safe && err != nil {
+			t.Errorf("Path(%q).CheckSafe() = %v, want nil", tt.name, err)
+		} else if !tt.safe && !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("Path(%q).CheckSafe() = %v, want ErrUnsafePath", tt.name, err)
+		}
+
+		fn, err := Path(tt.name).JoinHost("out")
+		if tt.safe && (err != nil || fn != filepath.Join("out", Path(tt.name).HostPath())) {
+			t.Errorf("Path(%q).JoinHost(\"out\") = %q, %v, want a path within out", tt.name, fn, err)
+		} else if !tt.safe && (err == nil || fn != "") {
+			t.Errorf("Path(%q).JoinHost(\"out\") = %q, %v, want ErrUnsafePath", tt.name, fn, err)
+		}
+	}
+}
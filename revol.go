@@ -1,55 +1,151 @@
 package vol
 
 import (
+	"bufio"
 	"encoding/binary"
+	"fmt"
+	"io"
 )
 
-func (v File) Store(buf *ByteBuffer) {
-	hdrPayload := headerAndPayload{IsPVOL: true}
-	fnFooter := filenameFooter{}
-	itFooter := itemFooter{}
+// storeLayout is the layout of a vol file as written by Store and WriteTo. It is computed up front, before anything is
+// written, so that output can be allocated once (Store) or streamed (WriteTo) in a single pass.
+type storeLayout struct {
+	HeaderMagic string     // magic of the header block (which depends on the format)
+	PayloadLen  uint32     // length of the header block's payload (all item blocks)
+	Owners      []int      // for each item, the item whose block holds its payload (see File.payloadOwners)
+	Footer      ByteBuffer // filenameFooter and itemFooter, fully encoded
+}
 
-	const hdrLen = blockHeaderLen // length of header before payload
-	for _, item := range v.Items {
-		itemOff := hdrLen + len(hdrPayload.Payload)
-		payloadItem{Payload: item.Payload}.Store(&hdrPayload.Payload)
-		// TODO: add special case to convert 0-length block to have 1-length header in pitem?
+// Len returns the total length of the vol file.
+func (l *storeLayout) Len() int64 {
+	return blockHeaderLen + int64(l.PayloadLen) + int64(len(l.Footer))
+}
 
-		fnFooter.Filenames = append(fnFooter.Filenames, item.Filename)
-		itFooter.Items = append(itFooter.Items, itemHeader{
-			Offset:      uint32(itemOff),
-			Compression: None,
-			PayloadLen:  uint32(len(item.Payload)),
-		})
+// Store appends the encoded vol file to buf. buf is grown at most once. The output depends only on v's fields and
+// items, in order (see Sort), so equal vols are always stored byte-identically. An error is returned if the vol cannot
+// be encoded (e.g. a filename cannot be represented in v.Encoding); nothing is appended in that case.
+func (v *File) Store(buf *ByteBuffer) error {
+	layout, err := v.layout()
+	if err != nil {
+		return err
 	}
+	buf.Grow(int(layout.Len()))
+	return v.write(buf, &layout) // writing to a ByteBuffer never fails
+}
 
-	hdrPayload.Store(buf)
-	fnFooter.Store(buf)
-	itFooter.Store(buf)
+// StoredLen returns the length of the vol file Store would write, or an error if it cannot be encoded (see Store).
+func (v *File) StoredLen() (int64, error) {
+	layout, err := v.layout()
+	if err != nil {
+		return 0, err
+	}
+	return layout.Len(), nil
 }
 
-func (v headerAndPayload) Store(buf *ByteBuffer) {
-	if !v.IsPVOL {
-		panic("non-PVOL unsupported")
+// WriteTo writes the encoded vol file to w. Item payloads are written to w directly, without copying. If the vol
+// cannot be encoded (see Store), an error is returned before anything is written.
+func (v *File) WriteTo(w io.Writer) (int64, error) {
+	layout, err := v.layout()
+	if err != nil {
+		return 0, err
 	}
-	block{HeaderMagic: magicPVOL, Payload: v.Payload}.Store(true, buf)
+
+	cw := countingWriter{W: w}
+	bw := bufio.NewWriter(&cw)
+	if err := v.write(bw, &layout); err != nil {
+		return cw.N, err
+	}
+	err = bw.Flush()
+	return cw.N, err
 }
 
-func (v filenameFooter) Store(buf *ByteBuffer) {
-	blk := block{HeaderMagic: magicVOLS}
-	for _, fn := range v.Filenames {
-		blk.Payload.AppendString(fn)
-		blk.Payload.Append(0) // null terminator
+func (v *File) layout() (storeLayout, error) {
+	var (
+		layout   storeLayout
+		fnLen    int
+		itemHdrs = make([]itemHeader, len(v.Items))
+	)
+
+	switch v.Format {
+	case FormatPVOL:
+		layout.HeaderMagic = magicPVOL
+	case FormatVOL:
+		if len(v.VOLHeaders) != volHeadersLen {
+			return storeLayout{}, fmt.Errorf("cannot store %s format without the %d bytes of VOLHeaders from an existing vol (have %d)", v.Format, volHeadersLen, len(v.VOLHeaders))
+		}
+		layout.HeaderMagic = magicVOL
+	default:
+		return storeLayout{}, fmt.Errorf("cannot store unknown format %s", v.Format)
+	}
+
+	layout.Owners = v.payloadOwners()
+	offset := uint32(blockHeaderLen) // the first item block follows the header
+	for i, item := range v.Items {
+		// TODO: add special case to convert 0-length block to have 1-length header in pitem?
+		itemHdrs[i] = itemHeader{
+			Unknown1:    item.Unknown1,
+			Unknown2:    item.Unknown2,
+			Offset:      offset,
+			Compression: item.Compression,
+			PayloadLen:  uint32(len(item.Payload)),
+		}
+		if owner := layout.Owners[i]; owner != i {
+			itemHdrs[i].Offset = itemHdrs[owner].Offset // shares the owner's block
+		} else {
+			offset += blockHeaderLen + uint32(len(item.Payload))
+		}
+		fnLen += len(item.Filename) + 1 // plus null terminator
 	}
-	blk.Store(false, buf)
+	layout.PayloadLen = offset - blockHeaderLen
+
+	layout.Footer = make(ByteBuffer, 0, len(v.VOLHeaders)+2*blockHeaderLen+fnLen+itemHeaderLen*len(v.Items))
+	if v.Format == FormatVOL {
+		layout.Footer.Append(v.VOLHeaders...)
+	}
+	block{HeaderMagic: magicVOLS}.StoreHeader(uint32(fnLen), false, &layout.Footer)
+	fnStart := len(layout.Footer)
+	for _, item := range v.Items {
+		if err := v.Encoding.Encode(item.Filename, &layout.Footer); err != nil {
+			return storeLayout{}, err
+		}
+		layout.Footer.Append(0) // null terminator
+	}
+	if encLen := len(layout.Footer) - fnStart; encLen != fnLen {
+		// Encoding changed the filenames' lengths; fix up the length in the header (its last 4 bytes)
+		binary.LittleEndian.PutUint32(layout.Footer[fnStart-4:], uint32(encLen))
+	}
+	block{HeaderMagic: magicVOLI}.StoreHeader(uint32(itemHeaderLen*len(itemHdrs)), false, &layout.Footer)
+	for _, hdr := range itemHdrs {
+		hdr.Store(&layout.Footer)
+	}
+
+	return layout, nil
 }
 
-func (v itemFooter) Store(buf *ByteBuffer) {
-	blk := block{HeaderMagic: magicVOLI}
-	for _, it := range v.Items {
-		it.Store(&blk.Payload)
+// write writes the vol file with the given layout (which must be v.layout()) to w.
+func (v *File) write(w io.Writer, layout *storeLayout) error {
+	hdr := make(ByteBuffer, 0, blockHeaderLen)
+
+	block{HeaderMagic: layout.HeaderMagic}.StoreHeader(layout.PayloadLen, true, &hdr)
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+
+	for i, item := range v.Items {
+		if layout.Owners[i] != i {
+			continue // payload already written
+		}
+		hdr = hdr[:0]
+		block{HeaderMagic: magicVBLK}.StoreHeader(uint32(len(item.Payload)), false, &hdr)
+		if _, err := w.Write(hdr); err != nil {
+			return err
+		} else if _, err := w.Write(item.Payload); err != nil {
+			return err
+		}
 	}
-	blk.Store(false, buf)
+
+	_, err := w.Write(layout.Footer)
+	return err
 }
 
 func (v itemHeader) Store(buf *ByteBuffer) {
@@ -60,6 +156,14 @@ func (v itemHeader) Store(buf *ByteBuffer) {
 	buf.Extend(1)[0] = byte(v.Compression)
 }
 
-func (v payloadItem) Store(buf *ByteBuffer) {
-	block{HeaderMagic: magicVBLK, Payload: v.Payload}.Store(false, buf)
+// countingWriter counts the bytes written through it to W.
+type countingWriter struct {
+	W io.Writer
+	N int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.W.Write(p)
+	w.N += int64(n)
+	return n, err
 }
@@ -0,0 +1,43 @@
+package vol
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+// benchmarkFile returns a vol of n small items, as in a typical mod.
+func benchmarkFile(n int) *File {
+	v := &File{}
+	payload := make([]byte, 200)
+	for i := 0; i < n; i++ {
+		_ = v.Add(Item{Filename: fmt.Sprintf("dir%d\\file%d.cs", i%100, i), Compression: None, Payload: payload})
+	}
+	return v
+}
+
+func BenchmarkStore(b *testing.B) {
+	v := benchmarkFile(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf ByteBuffer
+		if err := v.Store(&buf); err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(buf)))
+	}
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	v := benchmarkFile(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n, err := v.WriteTo(io.Discard)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(n)
+	}
+}
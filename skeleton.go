@@ -0,0 +1,84 @@
+package vol
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// Skeleton is a vol file with the payloads of its items cut out. Together with the payloads, it rebuilds the original
+// file exactly (see Rebuild), including any layout details that Store does not reproduce, such as padding, unusual
+// block headers, or items sharing a payload. It contains no item content, so it is small.
+type Skeleton struct {
+	Data    []byte   `json:"data"`    // the vol file, minus payloads
+	Splices []Splice `json:"splices"` // where to insert payloads into Data, in ascending order of At
+}
+
+// Splice is the position of an item's payload in a Skeleton.
+type Splice struct {
+	At   int `json:"at"`   // offset in Skeleton.Data
+	Item int `json:"item"` // index of the item whose payload is inserted
+	Len  int `json:"len"`  // length of the payload
+}
+
+// Skeleton returns the Skeleton of data, which must be the vol file v was parsed from (with v.Items unmodified).
+func (v *File) Skeleton(data []byte) (*Skeleton, error) {
+	type payloadRange struct{ start, end, item int }
+	var ranges []payloadRange
+	for i, item := range v.Items {
+		if len(item.Payload) == 0 {
+			continue
+		}
+		start, end := item.payloadOffset, item.payloadOffset+len(item.Payload)
+		if start == 0 || end > len(data) || !bytes.Equal(data[start:end], item.Payload) {
+			return nil, fmt.Errorf("item %d (%s) was not parsed from this data", i, item.Filename)
+		}
+		ranges = append(ranges, payloadRange{start, end, i})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	var (
+		skel Skeleton
+		pos  int
+	)
+	for _, r := range ranges {
+		if r.start < pos { // shares a payload already cut out
+			if r.end > pos {
+				return nil, fmt.Errorf("item %d (%s) partially overlaps another item's payload", r.item, v.Items[r.item].Filename)
+			}
+			continue
+		}
+		skel.Data = append(skel.Data, data[pos:r.start]...)
+		skel.Splices = append(skel.Splices, Splice{At: len(skel.Data), Item: r.item, Len: r.end - r.start})
+		pos = r.end
+	}
+	skel.Data = append(skel.Data, data[pos:]...)
+
+	return &skel, nil
+}
+
+// Rebuild reinserts the payloads of items into the skeleton, returning the original vol file. It is an error if any
+// payload has a different length than the original; other changes to items (names, etc.) are not detected, and are not
+// reflected in the result.
+func (s *Skeleton) Rebuild(items []Item) ([]byte, error) {
+	size := len(s.Data)
+	for _, sp := range s.Splices {
+		if sp.Item < 0 || sp.Item >= len(items) {
+			return nil, fmt.Errorf("skeleton refers to item %d, but there are only %d items", sp.Item, len(items))
+		} else if l := len(items[sp.Item].Payload); l != sp.Len {
+			return nil, fmt.Errorf("item %d (%s) has length %d, but its original length was %d", sp.Item, items[sp.Item].Filename, l, sp.Len)
+		}
+		size += sp.Len
+	}
+
+	out, pos := make([]byte, 0, size), 0
+	for _, sp := range s.Splices {
+		if sp.At < pos || sp.At > len(s.Data) {
+			return nil, fmt.Errorf("invalid skeleton splice position %d", sp.At)
+		}
+		out = append(out, s.Data[pos:sp.At]...)
+		out = append(out, items[sp.Item].Payload...)
+		pos = sp.At
+	}
+	return append(out, s.Data[pos:]...), nil
+}
@@ -0,0 +1,77 @@
+package vol
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortOrder is an order of items in a vol file (see File.Sort).
+type SortOrder byte
+
+const (
+	SortNone = SortOrder(iota) // keep items in the order they were added
+	SortName                   // by name, ignoring case and treating '/' as '\' (so directories stay together)
+	SortSize                   // by (decompressed) size, smallest first, then by name
+)
+
+// ParseSortOrder returns the SortOrder with the given name (as returned by SortOrder.String).
+func ParseSortOrder(name string) (SortOrder, error) {
+	for _, o := range []SortOrder{SortNone, SortName, SortSize} {
+		if strings.EqualFold(name, o.String()) {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown sort order %q (expected %s, %s or %s)", name, SortNone, SortName, SortSize)
+}
+
+func (o SortOrder) String() string {
+	switch o {
+	case SortNone:
+		return "none"
+	case SortName:
+		return "name"
+	case SortSize:
+		return "size"
+	default:
+		return fmt.Sprintf("SortOrder(%d)", byte(o))
+	}
+}
+
+// Sort reorders v.Items by o. The result depends only on the items themselves, not their current order, so (as Store
+// output depends only on v's fields and items) sorting makes the stored vol independent of the order files were found
+// or added in. Names differing only in case are ordered by their exact bytes, so the order is total.
+func (v *File) Sort(o SortOrder) {
+	var less func(a, b *Item) bool
+	switch o {
+	case SortName:
+		less = lessByName
+	case SortSize:
+		less = func(a, b *Item) bool {
+			if sa, sb := a.sortSize(), b.sortSize(); sa != sb {
+				return sa < sb
+			}
+			return lessByName(a, b)
+		}
+	default:
+		return
+	}
+
+	sort.SliceStable(v.Items, func(i, j int) bool { return less(&v.Items[i], &v.Items[j]) })
+	v.Reindex()
+}
+
+func lessByName(a, b *Item) bool {
+	if ka, kb := Path(a.Filename).Key(false), Path(b.Filename).Key(false); ka != kb {
+		return ka < kb
+	}
+	return a.Filename < b.Filename
+}
+
+// sortSize returns the item's decompressed size if known, else its payload size.
+func (v *Item) sortSize() int64 {
+	if size, ok := v.DecodedLen(); ok {
+		return size
+	}
+	return int64(len(v.Payload))
+}
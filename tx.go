@@ -0,0 +1,96 @@
+package vol
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Tx is a batch of edits to a vol file in progress; see Edit. All the editing methods of File (Add, Replace, Remove,
+// Rename, etc.) are available, and apply only to the in-memory copy until the Tx is committed.
+type Tx struct {
+	*File
+}
+
+// Recompress converts the payload of the item named name to compression type c.
+func (tx *Tx) Recompress(name string, c CompressionType) error {
+	item, ok := tx.Lookup(name)
+	if !ok {
+		return fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	if err := item.Recompress(c); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Edit applies a batch of edits to the vol file at path. The file is read and parsed (or, if it does not exist, an empty
+// vol is used), and fn is called to make changes. If fn returns nil, the result is committed with WriteFile, so path is
+// either left untouched or completely replaced, never partially written. If fn returns an error, nothing is written and
+// that error is returned. The result is always in FormatPVOL (see File.VOLHeaders).
+func Edit(path string, fn func(tx *Tx) error) error {
+	var v File
+	if data, err := os.ReadFile(path); os.IsNotExist(err) {
+		// nothing to do; leave v empty
+	} else if err != nil {
+		return fmt.Errorf("could not read vol file %s: %w", path, err)
+	} else if err := v.Parse(data); err != nil {
+		return fmt.Errorf("could not parse vol file %s: %w", path, err)
+	}
+	v.Format, v.VOLHeaders = FormatPVOL, nil // see File.VOLHeaders
+
+	if err := fn(&Tx{File: &v}); err != nil {
+		return err
+	}
+	return WriteFile(path, &v)
+}
+
+// WriteFile stores v to path atomically: the new content is written to a temporary file in the same directory, synced
+// to disk, then renamed over path. If anything fails, path is left untouched and the temporary file is removed.
+func WriteFile(path string, v *File) error {
+	return WriteFileFrom(path, v)
+}
+
+// WriteFileFrom is like WriteFile, but writes whatever content src produces (e.g. a vol rebuilt by Skeleton.Rebuild,
+// via bytes.Reader).
+func WriteFileFrom(path string, src io.WriterTo) (err error) {
+	perm := os.FileMode(0644)
+	if stat, err := os.Stat(path); err == nil {
+		perm = stat.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return fmt.Errorf("could not create temporary file for %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := src.WriteTo(tmp); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	} else if err := tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("could not set permissions of temporary file %s: %w", tmp.Name(), err)
+	} else if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("could not sync temporary file %s: %w", tmp.Name(), err)
+	} else if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not close temporary file %s: %w", tmp.Name(), err)
+	} else if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("could not replace %s: %w", path, err)
+	}
+
+	// Best effort: sync the directory so the rename itself is durable (not supported on all platforms)
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package vol
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Magic bytes of zip files (and so VL2 files): a local file header, or (for an empty zip) the end of central directory.
+const (
+	zipMagicLocal = "PK\x03\x04"
+	zipMagicEmpty = "PK\x05\x06"
+)
+
+// VL2Archive is an Archive of a VL2 file, the zip files Tribes 2 and later Torque games load content from. Names in
+// the zip use '/' as the separator, and are converted to and from Paths. Files written are compressed with deflate.
+// Limits apply as they do to vols: DefaultLimits when the archive is opened, and Limits (if not nil) when files are
+// opened. As in VolArchive, a name is looked up exactly first, and only then ignoring case, so that every file of a VL2
+// containing names that differ only in case can still be opened by its own name.
+type VL2Archive struct {
+	Path          string
+	CaseSensitive bool
+	Limits        *Limits
+
+	files []vl2File
+	index map[string]int // name key -> index in files (of the last file with that key)
+	exact map[string]int // exact name -> index in files
+}
+
+type vl2File struct {
+	Name Path
+	Zip  *zip.File // as read from Path, if not written since
+	Data []byte    // as written, if Zip is nil
+}
+
+func openVL2(path string, caseSensitive bool) (*VL2Archive, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s as a VL2 (zip) file: %w", path, err)
+	}
+
+	// As in File.Parse, DefaultLimits apply to the declared sizes (which reading enforces; see zip.File.Open)
+	a := &VL2Archive{Path: path, CaseSensitive: caseSensitive}
+	limits := a.limits()
+	if err := limits.checkItems(len(zr.File)); err != nil {
+		return nil, err
+	}
+	var totalSize int64
+	for _, f := range zr.File {
+		if f.Mode().IsDir() {
+			continue
+		}
+		size := int64(f.UncompressedSize64)
+		if f.UncompressedSize64 > math.MaxInt64 {
+			size = math.MaxInt64
+		}
+		if err := limits.checkFilename([]byte(f.Name)); err != nil {
+			return nil, err
+		} else if err := limits.checkItemSize(f.Name, size); err != nil {
+			return nil, err
+		} else if totalSize += size; totalSize < 0 {
+			totalSize = math.MaxInt64
+		}
+		if err := limits.checkTotalSize(totalSize); err != nil {
+			return nil, err
+		}
+		name := Path(f.Name).Clean()
+		if _, ok := a.exact[name.Key(true)]; ok {
+			return nil, fmt.Errorf("%s: %s: %w", path, name, ErrExists)
+		}
+		a.add(vl2File{Name: name, Zip: f})
+	}
+	return a, nil
+}
+
+// limits returns a.Limits, or DefaultLimits if nil.
+func (a *VL2Archive) limits() *Limits {
+	if a.Limits != nil {
+		return a.Limits
+	}
+	return &DefaultLimits
+}
+
+// add appends f to the archive's files, and indexes it.
+func (a *VL2Archive) add(f vl2File) {
+	if a.index == nil {
+		a.index, a.exact = make(map[string]int), make(map[string]int)
+	}
+	a.index[f.Name.Key(a.CaseSensitive)] = len(a.files)
+	a.exact[f.Name.Key(true)] = len(a.files)
+	a.files = append(a.files, f)
+}
+
+// indexOf returns the index of the file named name: exactly, or else ignoring case (unless CaseSensitive).
+func (a *VL2Archive) indexOf(name string) (int, bool) {
+	if idx, ok := a.exact[Path(name).Key(true)]; ok {
+		return idx, true
+	}
+	idx, ok := a.index[Path(name).Key(a.CaseSensitive)]
+	return idx, ok
+}
+
+func (f *vl2File) entry() Entry {
+	if f.Zip == nil {
+		return Entry{Name: f.Name, Size: int64(len(f.Data)), StoredSize: int64(len(f.Data)), Compression: "None"}
+	}
+	e := Entry{Name: f.Name, Size: int64(f.Zip.UncompressedSize64), StoredSize: int64(f.Zip.CompressedSize64)}
+	switch f.Zip.Method {
+	case zip.Store:
+		e.Compression = "None"
+	case zip.Deflate:
+		e.Compression = "deflate"
+	default:
+		e.Compression = fmt.Sprintf("zip method %d", f.Zip.Method)
+	}
+	return e
+}
+
+func (a *VL2Archive) List() []Entry {
+	entries := make([]Entry, len(a.files))
+	for i := range a.files {
+		entries[i] = a.files[i].entry()
+	}
+	return entries
+}
+
+func (a *VL2Archive) Stat(name string) (Entry, error) {
+	idx, ok := a.indexOf(name)
+	if !ok {
+		return Entry{}, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	return a.files[idx].entry(), nil
+}
+
+func (a *VL2Archive) Open(name string) ([]byte, error) {
+	idx, ok := a.indexOf(name)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	f := &a.files[idx]
+	if f.Zip == nil {
+		return f.Data, nil
+	}
+
+	if err := a.limits().checkItemSize(string(f.Name), int64(f.Zip.UncompressedSize64)); err != nil {
+		return nil, err
+	}
+	r, err := f.Zip.Open()
+	if errors.Is(err, zip.ErrAlgorithm) {
+		return nil, fmt.Errorf("%s: %w", f.Name, ErrUnsupportedCompression)
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (a *VL2Archive) Write(name string, data []byte) error {
+	if idx, ok := a.indexOf(name); ok {
+		// Replace the file in place; its name key (and so its entry in index) is unchanged
+		delete(a.exact, a.files[idx].Name.Key(true))
+		a.exact[Path(name).Key(true)] = idx
+		a.files[idx] = vl2File{Name: Path(name), Data: data}
+		return nil
+	}
+	a.add(vl2File{Name: Path(name), Data: data})
+	return nil
+}
+
+// Commit writes the archive to Path. Files not written since the archive was opened are copied without recompressing.
+func (a *VL2Archive) Commit() error {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range a.files {
+		if f.Zip != nil {
+			if err := zw.Copy(f.Zip); err != nil {
+				return fmt.Errorf("could not copy %s: %w", f.Name, err)
+			}
+			continue
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: strings.ReplaceAll(string(f.Name), string(PathSeparator), "/"), Method: zip.Deflate})
+		if err != nil {
+			return err
+		} else if _, err := w.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return WriteFileFrom(a.Path, &buf)
+}
+
+func (a *VL2Archive) Close() error {
+	a.files, a.index, a.exact = nil, nil, nil
+	return nil
+}
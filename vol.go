@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"strings"
 )
 
 // Magic bytes
@@ -25,14 +26,78 @@ const (
 	LZH  = CompressionType(3)
 )
 
+// Format is the variant of the vol file format.
+type Format byte
+
+const (
+	FormatPVOL = Format(iota) // "PVOL" format, used by Starsiege: Tribes (and the only format Store can create from scratch)
+	FormatVOL                 // " VOL" format, used by Starsiege
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatPVOL:
+		return "PVOL"
+	case FormatVOL:
+		return "VOL"
+	default:
+		return fmt.Sprintf("Format(%d)", byte(f))
+	}
+}
+
+// ParseFormat returns the Format with the given name (as returned by Format.String).
+func ParseFormat(name string) (Format, error) {
+	for _, f := range []Format{FormatPVOL, FormatVOL} {
+		if strings.EqualFold(name, f.String()) {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown vol format %q (expected %s or %s)", name, FormatPVOL, FormatVOL)
+}
+
 type File struct {
 	Items []Item
+
+	// Format is the variant of the vol file format, as parsed by Parse and written by Store.
+	Format Format
+
+	// VOLHeaders are, for FormatVOL, the 16 bytes (2 pairs of magic/offset headers, of unknown purpose) preceding the
+	// filenames footer. They are kept as parsed, and required by Store to write FormatVOL. Store writes them verbatim;
+	// as the offsets in them may depend on the layout of the vol, a vol whose items have changed is only written in
+	// FormatVOL when reproducing it exactly (see vol explode and vol build), and edits (Edit, VolArchive) write
+	// FormatPVOL.
+	VOLHeaders []byte
+
+	// CaseSensitive makes item lookup (Lookup, Has, Add, etc.) distinguish names differing only in case. By default,
+	// names are compared case-insensitively, as the engine does (see Path.Key). Call Reindex after changing it on a
+	// File that has already been used.
+	CaseSensitive bool
+
+	// Limits bounds the resources used by Parse, and by Decompress for the items parsed; if nil, DefaultLimits is used.
+	Limits *Limits
+
+	// Encoding is the encoding of filenames in the vol file, which Parse decodes from and Store encodes to.
+	Encoding Encoding
+
+	// Dedupe makes Store write each distinct payload only once, with the headers of all items having that payload
+	// pointing at the same item block. Parse sets it if the vol has items sharing a payload (see SharedPayloads), so
+	// that editing a deduped vol keeps it deduped.
+	Dedupe bool
+
+	index map[string]int // name key (see nameKey) -> index in Items; built lazily
 }
 
 type Item struct {
 	Filename    string
 	Compression CompressionType
 	Payload     ByteBuffer
+
+	// Unknown1 and Unknown2 are fields of the item's header with unknown purpose; they are kept as parsed, and written
+	// back verbatim by Store.
+	Unknown1, Unknown2 uint32
+
+	payloadOffset int     // offset of Payload in the data it was parsed from, or 0 if not parsed (see Skeleton)
+	limits        *Limits // Limits of the File it was parsed by, used by Decompress; nil for DefaultLimits
 }
 
 func (v *File) Parse(data []byte) error {
@@ -42,20 +107,31 @@ func (v *File) Parse(data []byte) error {
 		itFooter   itemFooter
 	)
 
+	limits := v.limits()
+
 	parseBuf := ByteBuffer(data)
 	if err := hdrPayload.Parse(&parseBuf); err != nil {
 		return err
-	} else if err := fnFooter.Parse(hdrPayload.IsPVOL, &parseBuf); err != nil {
+	} else if err := fnFooter.Parse(hdrPayload.IsPVOL, limits, v.Encoding, &parseBuf); err != nil {
 		return err
-	} else if err := itFooter.Parse(&parseBuf); err != nil {
+	} else if err := itFooter.Parse(limits, &parseBuf); err != nil {
 		return err
 	}
 
+	if hdrPayload.IsPVOL {
+		v.Format, v.VOLHeaders = FormatPVOL, nil
+	} else {
+		v.Format, v.VOLHeaders = FormatVOL, append([]byte(nil), fnFooter.VOLHeaders...)
+	}
+
 	if len(fnFooter.Filenames) != len(itFooter.Items) {
 		return fmt.Errorf("filenameFooter contains different number of filenames than itemFooter's number of item headers (%d vs. %d)", len(fnFooter.Filenames), len(itFooter.Items))
 	}
 
 	pstart, pend := hdrPayload.HeaderLen(), hdrPayload.HeaderLen()+uint32(len(hdrPayload.Payload))
+	var totalSize int64
+	offsets := make(map[uint32]bool, len(itFooter.Items)) // item block offsets seen so far, to detect shared payloads
+	v.Dedupe = false
 	for i, itemHdr := range itFooter.Items {
 		filename := fnFooter.Filenames[i]
 
@@ -64,7 +140,13 @@ func (v *File) Parse(data []byte) error {
 			return fmt.Errorf("item %d range [%d, %d) out of bounds in payload [%d, %d)", i, start, end, pstart, pend)
 		}
 
-		item := Item{Filename: filename, Compression: None}
+		item := Item{
+			Filename:    filename,
+			Compression: itemHdr.Compression,
+			Unknown1:    itemHdr.Unknown1,
+			Unknown2:    itemHdr.Unknown2,
+			limits:      v.Limits,
+		}
 
 		// Stupid special case: for zero-length item, itemHeader reports length 0, but the header on the Item itself
 		// reports length 1, so we may crash to parse it. So for length 0, just append an empty Item, don't read payload.
@@ -74,11 +156,25 @@ func (v *File) Parse(data []byte) error {
 			if err := pitem.Parse(&itemBuf); err != nil {
 				return fmt.Errorf("parsing item %d (%s) at range [%d, %d): %w", i, filename, start, end, err)
 			}
-			item.Payload = pitem.Payload
+			item.Payload, item.payloadOffset = pitem.Payload, int(start)+blockHeaderLen
+
+			// Several items may point at the same block (see Dedupe); they simply share the payload
+			v.Dedupe = v.Dedupe || offsets[start]
+			offsets[start] = true
+		}
+
+		if size, ok := item.DecodedLen(); ok {
+			totalSize += size
+			if err := limits.checkItemSize(filename, size); err != nil {
+				return err
+			} else if err := limits.checkTotalSize(totalSize); err != nil {
+				return err
+			}
 		}
 
 		v.Items = append(v.Items, item)
 	}
+	v.index = nil
 
 	return nil
 }
@@ -96,21 +192,14 @@ func (v *Item) Parse(filename string, hdr itemHeader, buf *ByteBuffer) error {
 	return nil
 }
 
-func (v *Item) Decompress() {
-	switch v.Compression {
-	case None:
-	default:
-		panic(fmt.Errorf("unsupported compression type %s", v.Compression))
-	}
-}
-
 type headerAndPayload struct {
 	IsPVOL  bool
 	Payload ByteBuffer
 }
 
 type filenameFooter struct {
-	Filenames []string
+	VOLHeaders ByteBuffer // non-PVOL only
+	Filenames  []string
 }
 
 type itemFooter struct {
@@ -125,6 +214,8 @@ type itemHeader struct {
 	Compression CompressionType
 }
 
+const volHeadersLen = 2 * (2 * 4)
+
 type payloadItem struct {
 	Payload ByteBuffer
 }
@@ -160,9 +251,13 @@ func (v *headerAndPayload) Parse(buf *ByteBuffer) (err error) {
 
 func (v *headerAndPayload) HeaderLen() uint32 { return blockHeaderLen }
 
-func (v *filenameFooter) Parse(isPVOL bool, buf *ByteBuffer) error {
+func (v *filenameFooter) Parse(isPVOL bool, limits *Limits, enc Encoding, buf *ByteBuffer) error {
 	if !isPVOL {
-		buf.Skip(2 * (2 * 4)) // non-PVOL filenameFooter has 2 extra pairs of magic/offset headers (unknown purpose) before the strings section
+		// non-PVOL filenameFooter has 2 extra pairs of magic/offset headers (unknown purpose) before the strings section
+		var ok bool
+		if v.VOLHeaders, ok = buf.Next(volHeadersLen); !ok {
+			return fmt.Errorf("unexpected end of filenameFooter VOL headers (expected %d bytes, only %d left)", volHeadersLen, len(*buf))
+		}
 	}
 
 	var filenamesBlock block
@@ -176,13 +271,18 @@ func (v *filenameFooter) Parse(isPVOL bool, buf *ByteBuffer) error {
 		}
 
 		fnBytes := filenamesBlock.Payload.MustNext(nulIdx + 1) // guaranteed by index check above
-		v.Filenames = append(v.Filenames, string(fnBytes[:nulIdx]))
+		if err := limits.checkFilename(fnBytes[:nulIdx]); err != nil {
+			return err
+		} else if err := limits.checkItems(len(v.Filenames) + 1); err != nil {
+			return err
+		}
+		v.Filenames = append(v.Filenames, enc.Decode(fnBytes[:nulIdx]))
 	}
 
 	return nil
 }
 
-func (v *itemFooter) Parse(buf *ByteBuffer) error {
+func (v *itemFooter) Parse(limits *Limits, buf *ByteBuffer) error {
 	// There is padding between the filenames and items footers; seek forward a limited distance to find the magic header
 	const maxSeek = 8
 	if padding := bytes.Index(*buf, []byte(magicVOLI)); padding > maxSeek {
@@ -196,6 +296,10 @@ func (v *itemFooter) Parse(buf *ByteBuffer) error {
 	if err := itemsBlock.Parse("filenameFooter items", magicVOLI, 0, false, buf); err != nil {
 		return err
 	}
+	if err := limits.checkItems(len(itemsBlock.Payload) / itemHeaderLen); err != nil {
+		return err
+	}
+	v.Items = make([]itemHeader, 0, len(itemsBlock.Payload)/itemHeaderLen)
 	for len(itemsBlock.Payload) > 0 {
 		var item itemHeader
 		if err := item.Parse(&itemsBlock.Payload); err != nil {
@@ -207,8 +311,10 @@ func (v *itemFooter) Parse(buf *ByteBuffer) error {
 	return nil
 }
 
+const itemHeaderLen = 4*4 + 1 // 4 uint32 + 1 byte
+
 func (v *itemHeader) Parse(buf *ByteBuffer) error {
-	if len(*buf) < 4*4+1 { // 4 uint32 + 1 byte
+	if len(*buf) < itemHeaderLen {
 		return fmt.Errorf("unexpected end of item")
 	}
 	v.Unknown1 = binary.LittleEndian.Uint32(buf.MustNext(4))